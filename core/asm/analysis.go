@@ -0,0 +1,747 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ValidJumpDests returns the set of offsets holding a JUMPDEST instruction.
+// Bytes inside push data are never considered, even if they equal JUMPDEST.
+func ValidJumpDests(script []byte) (map[uint64]bool, error) { log.DebugLog()
+	dests := make(map[uint64]bool)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.Op() == vm.JUMPDEST {
+			dests[it.PC()] = true
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return dests, nil
+}
+
+// InvalidOpcodes returns the offsets of all instructions whose opcode is not
+// defined by the EVM. The designated INVALID opcode is not reported.
+func InvalidOpcodes(script []byte) ([]uint64, error) { log.DebugLog()
+	var pcs []uint64
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if !it.IsValidOp() {
+			pcs = append(pcs, it.PC())
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return pcs, nil
+}
+
+// BasicBlock is a straight-line sequence of instructions with a single entry
+// and a single exit.
+type BasicBlock struct {
+	Start, End   uint64 // offsets of the first instruction and just past the last one
+	Instructions []Instruction
+}
+
+// BasicBlocks splits the code into basic blocks. A block ends after a JUMP,
+// JUMPI, STOP, RETURN, REVERT, SELFDESTRUCT or INVALID instruction. A JUMPDEST
+// always starts a new block, even if the previous one did not end with one of
+// those instructions and simply falls through into it.
+func BasicBlocks(script []byte) ([]BasicBlock, error) { log.DebugLog()
+	var (
+		blocks []BasicBlock
+		block  *BasicBlock
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.Op() == vm.JUMPDEST && block != nil {
+			blocks = append(blocks, *block)
+			block = nil
+		}
+		if block == nil {
+			block = &BasicBlock{Start: it.PC()}
+		}
+		block.Instructions = append(block.Instructions, it.Instruction())
+		block.End = it.nextPC()
+
+		// A conditional jump falls through, but still ends the block
+		if it.IsTerminator() || it.Op() == vm.JUMPI {
+			blocks = append(blocks, *block)
+			block = nil
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if block != nil {
+		blocks = append(blocks, *block)
+	}
+	return blocks, nil
+}
+
+// OpcodeHistogram counts the occurrences of every opcode in the code. If the
+// code is truncated, the counts tallied so far are returned with the error.
+func OpcodeHistogram(script []byte) (map[vm.OpCode]int, error) { log.DebugLog()
+	counts := make(map[vm.OpCode]int)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		counts[it.Op()]++
+	}
+	return counts, it.Error()
+}
+
+// FindOpcodes returns the offsets of every occurrence of the given opcodes,
+// keyed by opcode. Opcodes that do not occur are absent from the result.
+func FindOpcodes(script []byte, ops ...vm.OpCode) (map[vm.OpCode][]uint64, error) { log.DebugLog()
+	wanted := make(map[vm.OpCode]bool, len(ops))
+	for _, op := range ops {
+		wanted[op] = true
+	}
+	found := make(map[vm.OpCode][]uint64)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if wanted[it.Op()] {
+			found[it.Op()] = append(found[it.Op()], it.PC())
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// Discouraged opcodes reported by UsesDeprecated unless told otherwise.
+var deprecatedOpcodes = []vm.OpCode{vm.SELFDESTRUCT, vm.CALLCODE}
+
+// UsesDeprecated returns the offsets at which each of the given discouraged
+// opcodes occurs in the code. Without ops, SELFDESTRUCT and CALLCODE are
+// checked; pass a list to match local compliance rules.
+func UsesDeprecated(script []byte, ops ...vm.OpCode) (map[vm.OpCode][]uint64, error) { log.DebugLog()
+	if len(ops) == 0 {
+		ops = deprecatedOpcodes
+	}
+	return FindOpcodes(script, ops...)
+}
+
+// Opcodes reading the chain context reported by EnvironmentReads unless told
+// otherwise.
+var environmentOpcodes = []vm.OpCode{
+	vm.ADDRESS, vm.BALANCE, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.GASPRICE,
+	vm.EXTCODESIZE, vm.EXTCODECOPY, vm.BLOCKHASH, vm.COINBASE, vm.TIMESTAMP,
+	vm.NUMBER, vm.DIFFICULTY, vm.GASLIMIT,
+}
+
+// EnvironmentReads returns the offsets at which each of the given opcodes
+// occurs in the code, characterizing its dependence on the chain context.
+// Without ops, the opcodes reading the account, transaction and block context
+// are located; pass a list to adjust it to the analysis at hand.
+func EnvironmentReads(script []byte, ops ...vm.OpCode) (map[vm.OpCode][]uint64, error) { log.DebugLog()
+	if len(ops) == 0 {
+		ops = environmentOpcodes
+	}
+	return FindOpcodes(script, ops...)
+}
+
+// OpcodeSet returns the distinct opcodes used by the code, e.g. to check that
+// no opcode is newer than the targeted fork. If the code is truncated, the
+// opcodes decoded up to that point are returned together with the error.
+func OpcodeSet(script []byte) (map[vm.OpCode]struct{}, error) { log.DebugLog()
+	set := make(map[vm.OpCode]struct{})
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		set[it.Op()] = struct{}{}
+	}
+	return set, it.Error()
+}
+
+// PCToIndex maps the offset of every instruction to its position in the
+// instruction stream. Offsets inside push data are not included.
+func PCToIndex(script []byte) (map[uint64]int, error) { log.DebugLog()
+	pcs, err := InstructionBoundaries(script)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[uint64]int, len(pcs))
+	for i, pc := range pcs {
+		index[pc] = i
+	}
+	return index, nil
+}
+
+// InstructionBoundaries returns the offsets of all instructions in ascending
+// order. If the code is truncated, the offsets decoded up to that point are
+// returned together with the error.
+func InstructionBoundaries(script []byte) ([]uint64, error) { log.DebugLog()
+	pcs, _, err := instructionBoundaries(script, Latest, false)
+	return pcs, err
+}
+
+// instructionBoundaries returns the offsets of all instructions decoded
+// according to fork and leniency, and the offset at which decoding stopped:
+// the instruction that failed to decode, or the end of the last instruction.
+func instructionBoundaries(script []byte, fork Fork, lenient bool) ([]uint64, uint64, error) { log.DebugLog()
+	pcs := make([]uint64, 0, len(script)/2)
+
+	it := NewInstructionIteratorWithFork(script, fork)
+	it.lenient = lenient
+	for it.Next() {
+		pcs = append(pcs, it.PC())
+	}
+	return pcs, it.PC(), it.Error()
+}
+
+// searchBoundary returns the position of pc within the boundaries found by
+// instructionBoundaries, the offset end at which decoding stopped counting as
+// the position after the last one. The bool is false if pc is no boundary.
+func searchBoundary(pcs []uint64, end uint64, pc uint64) (int, bool) { log.DebugLog()
+	if pc == end {
+		return len(pcs), true
+	}
+	i := sort.Search(len(pcs), func(i int) bool { return pcs[i] >= pc })
+	return i, i < len(pcs) && pcs[i] == pc
+}
+
+// isJumpTarget returns whether the current instruction pushes a valid jump
+// destination that is consumed right away by a JUMP or JUMPI.
+func (it *instructionIterator) isJumpTarget(dests map[uint64]bool) bool { log.DebugLog()
+	if op, _, ok := it.Peek(); !ok || (op != vm.JUMP && op != vm.JUMPI) {
+		return false
+	}
+	return isValidDest(it.pushedValue(), dests)
+}
+
+// pushedValue returns the value pushed by the current instruction, which is
+// zero for PUSH0, or nil if it is no push.
+func (it *instructionIterator) pushedValue() *big.Int { log.DebugLog()
+	if it.op != vm.PUSH0 && !it.op.IsPush() {
+		return nil
+	}
+	if value := it.ArgBig(); value != nil {
+		return value
+	}
+	return new(big.Int)
+}
+
+// isValidDest returns whether a pushed value is one of the valid jump
+// destinations.
+func isValidDest(pushed *big.Int, dests map[uint64]bool) bool { log.DebugLog()
+	return pushed != nil && pushed.IsUint64() && dests[pushed.Uint64()]
+}
+
+// StorageAccess is an SLOAD or SSTORE instruction. Slot is only set if the
+// slot was pushed as a constant right before the access.
+type StorageAccess struct {
+	PC      uint64
+	Op      vm.OpCode
+	Slot    *big.Int
+	IsWrite bool
+}
+
+// StorageAccesses returns all storage reads and writes of the code, resolving
+// the accessed slot where it is a directly preceding push constant.
+func StorageAccesses(script []byte) ([]StorageAccess, error) { log.DebugLog()
+	var (
+		accesses []StorageAccess
+		pushed   *big.Int
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		op := it.Op()
+		if op == vm.SLOAD || op == vm.SSTORE {
+			accesses = append(accesses, StorageAccess{PC: it.PC(), Op: op, Slot: pushed, IsWrite: op == vm.SSTORE})
+		}
+		pushed = it.pushedValue()
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return accesses, nil
+}
+
+// JumpEdge is a control flow edge from a JUMP or JUMPI to its destination.
+type JumpEdge struct {
+	FromPC      uint64 // Offset of the JUMP or JUMPI instruction
+	ToPC        uint64 // Offset of the destination JUMPDEST, unset if Dynamic
+	Conditional bool   // Whether the jump is a JUMPI
+	Dynamic     bool   // Whether the destination is not a directly pushed constant
+}
+
+// JumpEdges returns the jumps whose destination is pushed right before them
+// and is a valid JUMPDEST, as well as the dynamic jumps whose destination
+// cannot be resolved that way. Jumps to pushed constants that are no valid
+// destination always fail and are omitted.
+func JumpEdges(script []byte) ([]JumpEdge, error) { log.DebugLog()
+	dests, err := ValidJumpDests(script)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		edges  []JumpEdge
+		pushed *big.Int
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		op := it.Op()
+		if op == vm.JUMP || op == vm.JUMPI {
+			edge := JumpEdge{FromPC: it.PC(), Conditional: op == vm.JUMPI}
+			switch {
+			case pushed == nil:
+				edge.Dynamic = true
+				edges = append(edges, edge)
+			case isValidDest(pushed, dests):
+				edge.ToPC = pushed.Uint64()
+				edges = append(edges, edge)
+			}
+		}
+		pushed = it.pushedValue()
+	}
+	return edges, nil
+}
+
+// Return all disassembled EVM instructions in human-readable format, marking
+// pushes of valid jump destinations that directly feed a JUMP or JUMPI.
+func DisassembleCompact(script []byte) ([]string, error) { log.DebugLog()
+	dests, err := ValidJumpDests(script)
+	if err != nil {
+		return nil, err
+	}
+	instrs := make([]string, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.isJumpTarget(dests) {
+			instrs = append(instrs, fmt.Sprintf("%v ; jump target\n", it.current()))
+		} else {
+			instrs = append(instrs, DefaultFormat(it.current()))
+		}
+	}
+	return instrs, nil
+}
+
+// Return all disassembled EVM instructions in human-readable format with jump
+// destinations named label_1, label_2, ... in code order. Every JUMPDEST is
+// preceded by its label marker on a line of its own, and pushes of a known
+// destination that directly feed a JUMP or JUMPI refer to it by name.
+func DisassembleLabeled(script []byte) ([]string, error) { log.DebugLog()
+	dests, err := ValidJumpDests(script)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[uint64]string, len(dests))
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.Op() == vm.JUMPDEST {
+			labels[it.PC()] = fmt.Sprintf("label_%d", len(labels)+1)
+		}
+	}
+	instrs := make([]string, 0, len(script)/2+len(labels))
+
+	it.Reset()
+	for it.Next() {
+		switch {
+		case it.Op() == vm.JUMPDEST:
+			instrs = append(instrs, labels[it.PC()]+":\n", DefaultFormat(it.current()))
+		case it.isJumpTarget(dests):
+			target := Instruction{PC: it.PC(), Op: it.Op()}
+			instrs = append(instrs, target.format(DefaultPCWidth)+" "+labels[it.pushedValue().Uint64()]+"\n")
+		default:
+			instrs = append(instrs, DefaultFormat(it.current()))
+		}
+	}
+	return instrs, nil
+}
+
+// Return all disassembled EVM instructions in human-readable format with both
+// the absolute PC and the offset from the most recent JUMPDEST, or from the
+// start of the code before the first one, e.g. "000123 (+0x0a): ADD".
+func DisassembleRelative(script []byte) ([]string, error) { log.DebugLog()
+	var (
+		instrs = make([]string, 0, len(script)/2)
+		base   uint64
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.Op() == vm.JUMPDEST {
+			base = it.PC()
+		}
+		instr := it.current()
+		pc := fmt.Sprintf("%s (+0x%02x)", instr.formatPC(DefaultPCWidth), instr.PC-base)
+		instrs = append(instrs, instr.formatWith(pc, it.OpString())+"\n")
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
+
+// FunctionSelectors heuristically enumerates the function selectors of a
+// contract by collecting the immediates of all PUSH4 instructions, which is
+// how Solidity dispatchers load the selectors they compare against. PUSH4 may
+// be used for other constants too, so the result is a first pass for ABI
+// recovery rather than an exact answer. Duplicates are dropped, keeping the
+// order of first appearance.
+func FunctionSelectors(script []byte) ([][4]byte, error) { log.DebugLog()
+	var (
+		selectors [][4]byte
+		seen      = make(map[[4]byte]bool)
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.Op() != vm.PUSH4 {
+			continue
+		}
+		var selector [4]byte
+		copy(selector[:], it.Arg())
+		if !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return selectors, nil
+}
+
+// OpcodeFingerprint returns the opcode sequence of the code with the push
+// immediates dropped and every push, regardless of its width and including
+// PUSH0, collapsed into a PUSH1 byte. Hashing the result yields a fingerprint
+// of the program structure that tolerates differing constants.
+func OpcodeFingerprint(script []byte) ([]byte, error) { log.DebugLog()
+	fingerprint := make([]byte, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.Op() == vm.PUSH0 || it.Op().IsPush() {
+			fingerprint = append(fingerprint, byte(vm.PUSH1))
+		} else {
+			fingerprint = append(fingerprint, byte(it.Op()))
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return fingerprint, nil
+}
+
+// FindSequence returns the offsets at which the given opcode sequence starts.
+// Matching only happens at instruction boundaries, so push data never causes
+// false hits. Overlapping matches are all reported.
+func FindSequence(script []byte, pattern []vm.OpCode) ([]uint64, error) { log.DebugLog()
+	var (
+		ops     []vm.OpCode
+		pcs     []uint64
+		matches []uint64
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		ops = append(ops, it.Op())
+		pcs = append(pcs, it.PC())
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if len(pattern) == 0 {
+		return nil, nil
+	}
+	for i := 0; i+len(pattern) <= len(ops); i++ {
+		match := true
+		for j, op := range pattern {
+			if ops[i+j] != op {
+				match = false
+				break
+			}
+		}
+		if match {
+			matches = append(matches, pcs[i])
+		}
+	}
+	return matches, nil
+}
+
+// constStack tracks the stack items whose values are known from preceding
+// pushes while scanning straight-line code. Unknown items are nil.
+type constStack []*big.Int
+
+// apply updates the stack with the effect of the current instruction.
+func (s *constStack) apply(it *instructionIterator) { log.DebugLog()
+	op := it.Op()
+	switch {
+	case op == vm.JUMPDEST:
+		// Jump targets may be entered with any stack, forget everything
+		*s = (*s)[:0]
+	case op == vm.PUSH0 || op.IsPush():
+		value := it.ArgBig()
+		if value == nil {
+			value = new(big.Int)
+		}
+		*s = append(*s, value)
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		*s = append(*s, s.peek(int(op-vm.DUP1)))
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		n := int(op-vm.SWAP1) + 1
+		if n < len(*s) {
+			top := len(*s) - 1
+			(*s)[top], (*s)[top-n] = (*s)[top-n], (*s)[top]
+		} else {
+			*s = (*s)[:0]
+		}
+	default:
+		pops, pushes := stackEffect(op)
+		if pops <= len(*s) {
+			*s = (*s)[:len(*s)-pops]
+		} else {
+			*s = (*s)[:0]
+		}
+		for i := 0; i < pushes; i++ {
+			*s = append(*s, nil)
+		}
+	}
+}
+
+// peek returns the n-th item from the top of the stack, or nil if unknown.
+func (s constStack) peek(n int) *big.Int { log.DebugLog()
+	if n < len(s) {
+		return s[len(s)-1-n]
+	}
+	return nil
+}
+
+// SplitInitRuntime separates creation code into the constructor instructions
+// and the runtime code it deploys. The runtime is identified by the CODECOPY
+// with constant offset and size that precedes the constructor's RETURN, as
+// emitted by the Solidity compiler. If no such pattern is found, the whole
+// code is returned as init code with an empty runtime.
+func SplitInitRuntime(script []byte) (initCode []Instruction, runtime []byte, err error) { log.DebugLog()
+	var (
+		stack         constStack
+		offset, size  uint64
+		found, copied bool
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		switch it.Op() {
+		case vm.CODECOPY:
+			off, sz := stack.peek(1), stack.peek(2)
+			copied = off != nil && sz != nil && off.IsUint64() && sz.IsUint64() &&
+				it.PC() < off.Uint64() && off.Uint64()+sz.Uint64() <= uint64(len(script))
+			if copied {
+				offset, size = off.Uint64(), sz.Uint64()
+			}
+		case vm.RETURN:
+			found = copied
+		}
+		if found {
+			break
+		}
+		stack.apply(it)
+	}
+	if !found {
+		instrs, err := DisassembleInstructions(script)
+		return instrs, []byte{}, err
+	}
+	if initCode, err = DisassembleInstructions(script[:offset]); err != nil {
+		return nil, nil, err
+	}
+	return initCode, script[offset : offset+size], nil
+}
+
+// InitCodeRef describes a CODECOPY whose copied bytes are used as the init
+// code of a subsequent CREATE or CREATE2. Offset and Length are only set if
+// both were pushed as constants, otherwise Dynamic is true.
+type InitCodeRef struct {
+	CopyPC   uint64    // Offset of the CODECOPY instruction
+	CreatePC uint64    // Offset of the CREATE or CREATE2 instruction
+	Op       vm.OpCode // CREATE or CREATE2
+	Offset   uint64    // Offset of the init code within the scanned code
+	Length   uint64    // Length of the init code
+	Dynamic  bool      // Whether offset or length are not statically known
+}
+
+// FindInitCodeCopies locates CODECOPY instructions feeding a CREATE or CREATE2
+// within the same basic block, as emitted for contract factories. The copied
+// range is resolved from preceding push constants where possible.
+func FindInitCodeCopies(script []byte) ([]InitCodeRef, error) { log.DebugLog()
+	var (
+		refs    []InitCodeRef
+		stack   constStack
+		pending *InitCodeRef
+	)
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		switch it.Op() {
+		case vm.JUMPDEST:
+			pending = nil
+		case vm.CODECOPY:
+			pending = &InitCodeRef{CopyPC: it.PC(), Dynamic: true}
+			if off, sz := stack.peek(1), stack.peek(2); off != nil && sz != nil && off.IsUint64() && sz.IsUint64() {
+				pending.Offset, pending.Length, pending.Dynamic = off.Uint64(), sz.Uint64(), false
+			}
+		case vm.CREATE, vm.CREATE2:
+			if pending != nil {
+				pending.CreatePC, pending.Op = it.PC(), it.Op()
+				refs = append(refs, *pending)
+				pending = nil
+			}
+		}
+		stack.apply(it)
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// Region is a span of code, from Start up to but excluding End.
+type Region struct {
+	Start, End uint64
+}
+
+// DataRegions heuristically locates embedded data by returning the spans of
+// at least minLen consecutive instructions that are undefined or INVALID. If
+// the code is truncated, the regions found so far are returned with the error.
+func DataRegions(script []byte, minLen int) ([]Region, error) { log.DebugLog()
+	var (
+		regions []Region
+		run     Region
+		count   int
+	)
+	flush := func() {
+		if count > 0 && count >= minLen {
+			regions = append(regions, run)
+		}
+		count = 0
+	}
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if it.IsValidOp() && it.Op() != vm.INVALID {
+			flush()
+			continue
+		}
+		if count == 0 {
+			run.Start = it.PC()
+		}
+		run.End = it.nextPC()
+		count++
+	}
+	flush()
+	return regions, it.Error()
+}
+
+// UnreachableRegions returns the spans of code that can never be executed
+// since they follow an instruction ending its block unconditionally, such as
+// STOP, RETURN or JUMP, and are not entered through a JUMPDEST. Embedded data
+// like the metadata trailer is reported as unreachable too.
+func UnreachableRegions(script []byte) ([]Region, error) { log.DebugLog()
+	return unreachableRegions(NewInstructionIterator(script))
+}
+
+// unreachableRegions implements UnreachableRegions on top of the given iterator,
+// so the strictness of push decoding can be chosen.
+func unreachableRegions(it *instructionIterator) ([]Region, error) { log.DebugLog()
+	var (
+		regions []Region
+		dead    bool
+		run     Region
+	)
+	for it.Next() {
+		if dead && it.Op() == vm.JUMPDEST {
+			if run.End = it.PC(); run.Start < run.End {
+				regions = append(regions, run)
+			}
+			dead = false
+		}
+		if !dead && it.IsTerminator() {
+			run.Start = it.nextPC()
+			dead = true
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if dead && run.Start < uint64(len(it.code)) {
+		run.End = uint64(len(it.code))
+		regions = append(regions, run)
+	}
+	return regions, nil
+}
+
+// EmbeddedString is a run of printable ASCII found in the data of the code.
+type EmbeddedString struct {
+	PC    uint64 // Offset of the first character
+	Value string // Printable characters
+}
+
+// ExtractStrings returns the runs of at least minLen printable ASCII characters
+// within the unreachable regions of the code, which is where compilers embed
+// data. Unlike the Unix strings tool, executable code is never scanned. Data
+// often ends in what decodes as a truncated push, which is not an error here.
+func ExtractStrings(script []byte, minLen int) ([]EmbeddedString, error) { log.DebugLog()
+	regions, err := unreachableRegions(NewInstructionIteratorStrict(script, true))
+	if err != nil {
+		return nil, err
+	}
+	var strs []EmbeddedString
+	for _, region := range regions {
+		start := region.Start
+		for pc := region.Start; pc <= region.End; pc++ {
+			if pc < region.End && script[pc] >= 0x20 && script[pc] <= 0x7e {
+				continue
+			}
+			if int(pc-start) >= minLen && pc > start {
+				strs = append(strs, EmbeddedString{PC: start, Value: string(script[start:pc])})
+			}
+			start = pc + 1
+		}
+	}
+	return strs, nil
+}
+
+// IsTrivial reports whether the code has no control flow: execution runs
+// straight from the first instruction to the first STOP, RETURN, REVERT,
+// INVALID or SELFDESTRUCT, or to the end of the code, without encountering a
+// JUMP or JUMPI. Anything after that point is unreachable and not inspected.
+// Empty code, which executes like a lone STOP, is trivial.
+func IsTrivial(script []byte) (bool, error) { log.DebugLog()
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		switch it.Op() {
+		case vm.JUMP, vm.JUMPI:
+			return false, nil
+		}
+		if it.IsTerminator() {
+			return true, nil
+		}
+	}
+	if err := it.Error(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,517 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that only real JUMPDEST instructions are reported
+func TestValidJumpDests(t *testing.T) { log.DebugLog()
+	// JUMPDEST PUSH1 0x5b JUMPDEST PUSH2 0x5b5b
+	script, _ := hex.DecodeString("5b605b5b615b5b")
+
+	dests, err := ValidJumpDests(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := map[uint64]bool{0: true, 3: true}
+	if !reflect.DeepEqual(dests, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, dests)
+	}
+
+	script, _ = hex.DecodeString("5b615b")
+	if _, err := ValidJumpDests(script); err == nil {
+		t.Errorf("Expected an error, but got none.")
+	}
+}
+
+// Tests that undefined opcodes are found while INVALID is accepted
+func TestInvalidOpcodes(t *testing.T) { log.DebugLog()
+	// STOP 0x0c PUSH1 0x0c INVALID 0xef 0x5c
+	script, _ := hex.DecodeString("000c600cfeef5c")
+
+	pcs, err := InvalidOpcodes(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []uint64{1, 5, 6}; !reflect.DeepEqual(pcs, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, pcs)
+	}
+}
+
+// Tests splitting code into basic blocks
+func TestBasicBlocks(t *testing.T) { log.DebugLog()
+	// PUSH1 0x05 JUMP | JUMPDEST ADD | JUMPDEST PUSH1 0x00 STOP | POP
+	script, _ := hex.DecodeString("6005565b015b60000050")
+
+	blocks, err := BasicBlocks(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := [][3]uint64{{0, 3, 2}, {3, 5, 2}, {5, 9, 3}, {9, 10, 1}}
+	if len(blocks) != len(want) {
+		t.Fatalf("Expected %d blocks, but got %d instead.", len(want), len(blocks))
+	}
+	for i, block := range blocks {
+		if block.Start != want[i][0] || block.End != want[i][1] || uint64(len(block.Instructions)) != want[i][2] {
+			t.Errorf("block %d: expected %v, but got start %d end %d with %d instructions.", i, want[i], block.Start, block.End, len(block.Instructions))
+		}
+	}
+}
+
+// Tests counting opcode occurrences while skipping push data
+func TestOpcodeHistogram(t *testing.T) { log.DebugLog()
+	// PUSH1 0x01 PUSH1 0x01 ADD PUSH2 (truncated)
+	script, _ := hex.DecodeString("600160010161")
+
+	counts, err := OpcodeHistogram(script)
+	if err == nil {
+		t.Errorf("Expected an error, but got none.")
+	}
+	want := map[vm.OpCode]int{vm.PUSH1: 2, vm.ADD: 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, counts)
+	}
+}
+
+// Tests mapping instruction offsets to their ordinal position
+func TestPCToIndex(t *testing.T) { log.DebugLog()
+	// PUSH2 0x0102 ADD PUSH1 0x03 STOP
+	script, _ := hex.DecodeString("61010201600300")
+
+	index, err := PCToIndex(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := map[uint64]int{0: 0, 3: 1, 4: 2, 6: 3}
+	if !reflect.DeepEqual(index, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, index)
+	}
+}
+
+// Tests that pushed jump targets are annotated
+func TestDisassembleCompact(t *testing.T) { log.DebugLog()
+	// PUSH2 0x0007 JUMP PUSH1 0x08 JUMPI JUMPDEST PUSH1 0x07 ADD
+	script, _ := hex.DecodeString("610007566008575b600701")
+
+	instrs, err := DisassembleCompact(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{
+		"000000: PUSH2 0x0007 ; jump target\n",
+		"000003: JUMP\n",
+		"000004: PUSH1 0x08\n",
+		"000006: JUMPI\n",
+		"000007: JUMPDEST\n",
+		"000008: PUSH1 0x07\n",
+		"000010: ADD\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+	// JUMPDEST PUSH0 JUMP, jumping back to the start
+	script, _ = hex.DecodeString("5b5f56")
+
+	instrs, err = DisassembleCompact(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want = []string{
+		"000000: JUMPDEST\n",
+		"000001: PUSH0 ; jump target\n",
+		"000002: JUMP\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+}
+
+// Tests listing the offsets of all instructions
+func TestInstructionBoundaries(t *testing.T) { log.DebugLog()
+	// PUSH2 0x5b5b JUMPDEST PUSH0 PUSH1 (truncated)
+	script, _ := hex.DecodeString("615b5b5b5f60")
+
+	pcs, err := InstructionBoundaries(script)
+	if err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+	if want := []uint64{0, 3, 4}; !reflect.DeepEqual(pcs, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, pcs)
+	}
+	if pcs, err := InstructionBoundaries(script[:5]); err != nil || len(pcs) != 3 {
+		t.Errorf("Expected 3 boundaries, but got %v (%v).", pcs, err)
+	}
+}
+
+// Tests flagging deprecated opcodes outside of push data
+func TestUsesDeprecated(t *testing.T) { log.DebugLog()
+	// PUSH1 0xff CALLCODE PUSH2 0xf2ff SELFDESTRUCT CALLCODE
+	script, _ := hex.DecodeString("60fff261f2ffff" + "f2")
+
+	found, err := UsesDeprecated(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := map[vm.OpCode][]uint64{vm.CALLCODE: {2, 7}, vm.SELFDESTRUCT: {6}}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, found)
+	}
+	if found, err := FindOpcodes(script, vm.ADD); err != nil || len(found) != 0 {
+		t.Errorf("Expected nothing found, but got %v (%v).", found, err)
+	}
+	if found, _ := UsesDeprecated(script, vm.SELFDESTRUCT); !reflect.DeepEqual(found, map[vm.OpCode][]uint64{vm.SELFDESTRUCT: {6}}) {
+		t.Errorf("Expected only SELFDESTRUCT, but got %v.", found)
+	}
+	if _, err := UsesDeprecated([]byte{0x61}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests locating reads of the chain context
+func TestEnvironmentReads(t *testing.T) { log.DebugLog()
+	// CALLER PUSH1 0x42 TIMESTAMP CALLDATASIZE NUMBER CALLER
+	script, _ := hex.DecodeString("3360424236" + "4333")
+
+	found, err := EnvironmentReads(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := map[vm.OpCode][]uint64{vm.CALLER: {0, 6}, vm.TIMESTAMP: {3}, vm.NUMBER: {5}}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, found)
+	}
+	// The set of opcodes is configurable
+	if found, _ := EnvironmentReads(script, vm.CALLDATASIZE); !reflect.DeepEqual(found, map[vm.OpCode][]uint64{vm.CALLDATASIZE: {4}}) {
+		t.Errorf("Expected only CALLDATASIZE, but got %v.", found)
+	}
+}
+
+// Tests collecting the distinct opcodes of the code
+func TestOpcodeSet(t *testing.T) { log.DebugLog()
+	// PUSH1 0x5f PUSH0 ADD PUSH0 PUSH2 (truncated)
+	script, _ := hex.DecodeString("605f5f015f61")
+
+	set, err := OpcodeSet(script)
+	if err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+	want := map[vm.OpCode]struct{}{vm.PUSH1: {}, vm.PUSH0: {}, vm.ADD: {}}
+	if !reflect.DeepEqual(set, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, set)
+	}
+}
+
+// Tests naming jump destinations in the disassembly
+func TestDisassembleLabeled(t *testing.T) { log.DebugLog()
+	// PUSH1 0x05 JUMPI PUSH1 0x05 JUMPDEST PUSH2 0x0004 JUMP JUMPDEST
+	script, _ := hex.DecodeString("6005576005" + "5b610004565b")
+
+	instrs, err := DisassembleLabeled(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{
+		"000000: PUSH1 label_1\n",
+		"000002: JUMPI\n",
+		"000003: PUSH1 0x05\n",
+		"label_1:\n",
+		"000005: JUMPDEST\n",
+		"000006: PUSH2 0x0004\n",
+		"000009: JUMP\n",
+		"label_2:\n",
+		"000010: JUMPDEST\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+	// JUMPDEST PUSH0 JUMP, jumping back to the start
+	script, _ = hex.DecodeString("5b5f56")
+
+	instrs, err = DisassembleLabeled(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want = []string{
+		"label_1:\n",
+		"000000: JUMPDEST\n",
+		"000001: PUSH0 label_1\n",
+		"000002: JUMP\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+}
+
+// Tests printing offsets relative to the last jump destination
+func TestDisassembleRelative(t *testing.T) { log.DebugLog()
+	// PUSH1 0x60 STOP JUMPDEST PUSH2 0x0102 ADD
+	script, _ := hex.DecodeString("6060005b61010201")
+
+	instrs, err := DisassembleRelative(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{
+		"000000 (+0x00): PUSH1 0x60\n",
+		"000002 (+0x02): STOP\n",
+		"000003 (+0x00): JUMPDEST\n",
+		"000004 (+0x01): PUSH2 0x0102\n",
+		"000007 (+0x04): ADD\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+}
+
+// Tests collecting the function selectors pushed by a dispatcher
+func TestFunctionSelectors(t *testing.T) { log.DebugLog()
+	// PUSH4 0xa9059cbb EQ PUSH4 0x70a08231 EQ PUSH4 0xa9059cbb PUSH2 0x0102
+	script, _ := hex.DecodeString("63a9059cbb14" + "6370a0823114" + "63a9059cbb" + "610102")
+
+	selectors, err := FunctionSelectors(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := [][4]byte{{0xa9, 0x05, 0x9c, 0xbb}, {0x70, 0xa0, 0x82, 0x31}}
+	if !reflect.DeepEqual(selectors, want) {
+		t.Errorf("Expected %x, but got %x instead.", want, selectors)
+	}
+	if _, err := FunctionSelectors([]byte{byte(vm.PUSH4), 0x01}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests that the fingerprint ignores push widths and immediates
+func TestOpcodeFingerprint(t *testing.T) { log.DebugLog()
+	// PUSH20 <address> PUSH0 BALANCE PUSH4 0xa9059cbb EQ
+	a, _ := hex.DecodeString("73" + strings.Repeat("11", 20) + "5f31" + "63a9059cbb14")
+	// PUSH1 0x00 PUSH2 0x0001 BALANCE PUSH1 0x01 EQ
+	b, _ := hex.DecodeString("6000" + "6100013160" + "0114")
+
+	fa, err := OpcodeFingerprint(a)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []byte{0x60, 0x60, 0x31, 0x60, 0x14}; !bytes.Equal(fa, want) {
+		t.Errorf("Expected %x, but got %x instead.", want, fa)
+	}
+	if fb, _ := OpcodeFingerprint(b); !bytes.Equal(fa, fb) {
+		t.Errorf("Expected equal fingerprints, but got %x and %x.", fa, fb)
+	}
+	if _, err := OpcodeFingerprint([]byte{0x61}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests locating storage accesses and their constant slots
+func TestStorageAccesses(t *testing.T) { log.DebugLog()
+	// PUSH1 0x03 SLOAD CALLER PUSH0 SSTORE CALLDATALOAD SLOAD
+	script, _ := hex.DecodeString("60035433" + "5f55" + "3554")
+
+	accesses, err := StorageAccesses(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []StorageAccess{
+		{PC: 2, Op: vm.SLOAD, Slot: big.NewInt(3)},
+		{PC: 5, Op: vm.SSTORE, Slot: new(big.Int), IsWrite: true},
+		{PC: 7, Op: vm.SLOAD},
+	}
+	if len(accesses) != len(want) {
+		t.Fatalf("Expected %d accesses, but got %+v.", len(want), accesses)
+	}
+	for i, w := range want {
+		a := accesses[i]
+		if a.PC != w.PC || a.Op != w.Op || a.IsWrite != w.IsWrite || (a.Slot == nil) != (w.Slot == nil) || (a.Slot != nil && a.Slot.Cmp(w.Slot) != 0) {
+			t.Errorf("access %d: expected %+v, but got %+v.", i, w, a)
+		}
+	}
+}
+
+// Tests resolving the destinations of jumps
+func TestJumpEdges(t *testing.T) { log.DebugLog()
+	// PUSH1 0x0b JUMPI PUSH1 0x0a JUMP CALLDATALOAD JUMP PUSH1 0x03 JUMPDEST JUMPDEST PUSH0 JUMP
+	script, _ := hex.DecodeString("600b57600a56355660035b5b5f56")
+
+	edges, err := JumpEdges(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []JumpEdge{
+		{FromPC: 2, ToPC: 11, Conditional: true},
+		{FromPC: 5, ToPC: 10},
+		{FromPC: 7, Dynamic: true},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("Expected %+v, but got %+v instead.", want, edges)
+	}
+}
+
+// Tests searching for opcode sequences at instruction boundaries
+func TestFindSequence(t *testing.T) { log.DebugLog()
+	// PUSH2 0x8056 DUP1 JUMP DUP1 DUP1 JUMP
+	script, _ := hex.DecodeString("618056805680805680")
+
+	pcs, err := FindSequence(script, []vm.OpCode{vm.DUP1, vm.JUMP})
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []uint64{3, 6}; !reflect.DeepEqual(pcs, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, pcs)
+	}
+	if pcs, _ := FindSequence(script, nil); len(pcs) != 0 {
+		t.Errorf("Expected no matches for an empty pattern, but got %v.", pcs)
+	}
+}
+
+// Tests splitting creation code into constructor and runtime
+func TestSplitInitRuntime(t *testing.T) { log.DebugLog()
+	// PUSH1 0x03 DUP1 PUSH1 0x0c PUSH1 0x00 CODECOPY PUSH1 0x00 RETURN INVALID
+	// followed by the runtime PUSH1 0x2a STOP
+	script, _ := hex.DecodeString("600380600c6000396000f3fe602a00")
+
+	initCode, runtime, err := SplitInitRuntime(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if hex.EncodeToString(runtime) != "602a00" {
+		t.Errorf("Expected runtime 602a00, but got %x instead.", runtime)
+	}
+	if len(initCode) != 8 || initCode[7].Op != vm.INVALID {
+		t.Errorf("Expected 8 init instructions ending in INVALID, but got %+v.", initCode)
+	}
+
+	// Without the pattern everything is init code
+	script, _ = hex.DecodeString("6001600201")
+	initCode, runtime, err = SplitInitRuntime(script)
+	if err != nil || len(initCode) != 3 || len(runtime) != 0 {
+		t.Errorf("Expected 3 init instructions and no runtime, but got %d, %x (%v).", len(initCode), runtime, err)
+	}
+}
+
+// Tests locating init code copied for contract creation
+func TestFindInitCodeCopies(t *testing.T) { log.DebugLog()
+	// PUSH1 0x20 PUSH1 0x40 PUSH1 0x00 CODECOPY PUSH1 0x00 PUSH1 0x20 PUSH1 0x00 PUSH1 0x00 CREATE2
+	// JUMPDEST CALLDATASIZE PUSH1 0x00 PUSH1 0x00 CODECOPY PUSH1 0x00 PUSH1 0x00 PUSH1 0x00 CREATE
+	script, _ := hex.DecodeString("60206040600039" + "6000602060006000f5" +
+		"5b3660006000396000600060" + "00f0")
+
+	refs, err := FindInitCodeCopies(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []InitCodeRef{
+		{CopyPC: 6, CreatePC: 15, Op: vm.CREATE2, Offset: 0x40, Length: 0x20},
+		{CopyPC: 22, CreatePC: 29, Op: vm.CREATE, Dynamic: true},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("Expected %+v, but got %+v instead.", want, refs)
+	}
+}
+
+// Tests locating dead code after block terminators
+func TestUnreachableRegions(t *testing.T) { log.DebugLog()
+	// PUSH1 0x07 JUMP ADD POP STOP JUMPDEST JUMPDEST RETURN CALLER
+	script, _ := hex.DecodeString("6007560150005b5bf333")
+
+	regions, err := UnreachableRegions(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []Region{{3, 6}, {9, 10}}; !reflect.DeepEqual(regions, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, regions)
+	}
+	// Terminators directly followed by a JUMPDEST or the end leave no gap
+	script, _ = hex.DecodeString("00" + "5b" + "00")
+	if regions, err := UnreachableRegions(script); err != nil || len(regions) != 0 {
+		t.Errorf("Expected no regions, but got %v (%v).", regions, err)
+	}
+}
+
+// Tests extracting printable strings from the data of the code
+func TestExtractStrings(t *testing.T) { log.DebugLog()
+	// PUSH1 0x41 PUSH1 0x42 STOP followed by the data "hello", 0x00, "ab", 0xff, "world"
+	script := append([]byte{0x60, 0x41, 0x60, 0x42, 0x00}, "hello\x00ab\xffworld"...)
+
+	strs, err := ExtractStrings(script, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []EmbeddedString{{5, "hello"}, {14, "world"}}
+	if !reflect.DeepEqual(strs, want) {
+		t.Errorf("Expected %+v, but got %+v instead.", want, strs)
+	}
+	// Printable immediates in reachable code are ignored
+	if strs, err := ExtractStrings([]byte{0x64, 'h', 'e', 'l', 'l', 'o'}, 3); err != nil || len(strs) != 0 {
+		t.Errorf("Expected no strings, but got %+v (%v).", strs, err)
+	}
+}
+
+// Tests classifying code without control flow
+func TestIsTrivial(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		code    string
+		trivial bool
+		fail    bool
+	}{
+		{"", true, false},
+		{"00", true, false},
+		// PUSH1 0x20 PUSH0 RETURN followed by unreachable jumps
+		{"60205ff3" + "5b600056", true, false},
+		// PUSH1 0x56 CALLER SSTORE, the jump opcode is push data
+		{"60563355", true, false},
+		// PUSH1 0x04 JUMP INVALID JUMPDEST STOP
+		{"600456fe5b00", false, false},
+		// CALLDATASIZE PUSH1 0x00 JUMPI STOP
+		{"3660005700", false, false},
+		// PUSH2 (truncated) before any terminator
+		{"6101", false, true},
+		// STOP followed by truncated data is never decoded
+		{"0061", true, false},
+	}
+	for _, test := range tests {
+		script, _ := hex.DecodeString(test.code)
+		trivial, err := IsTrivial(script)
+		if (err != nil) != test.fail || trivial != test.trivial {
+			t.Errorf("code %s: expected trivial %v (failure %v), but got %v (%v).", test.code, test.trivial, test.fail, trivial, err)
+		}
+	}
+}
+
+// Tests locating runs of undefined opcodes
+func TestDataRegions(t *testing.T) { log.DebugLog()
+	// PUSH1 0x00 | 0x0c 0x0d INVALID | ADD | 0xef | STOP | 0x21 0x22 0x23
+	script, _ := hex.DecodeString("60000c0dfe01ef00212223")
+
+	regions, err := DataRegions(script, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []Region{{2, 5}, {8, 11}}; !reflect.DeepEqual(regions, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, regions)
+	}
+}
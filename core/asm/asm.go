@@ -18,13 +18,75 @@
 package asm
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
 )
 
+// Instruction is a single decoded EVM instruction.
+type Instruction struct {
+	PC  uint64    // offset of the instruction within the code
+	Op  vm.OpCode // opcode of the instruction
+	Arg []byte    // immediate argument, only set for push instructions
+}
+
+// String renders the instruction in the disassembler's format, without a
+// trailing newline.
+func (instr Instruction) String() string { log.DebugLog()
+	return instr.format(DefaultPCWidth)
+}
+
+// Equal returns whether both instructions have the same opcode and argument,
+// regardless of their PCs. A missing argument equals an empty one.
+func (instr Instruction) Equal(o Instruction) bool { log.DebugLog()
+	return instr.Op == o.Op && bytes.Equal(instr.Arg, o.Arg)
+}
+
+// EqualIgnoringArg returns whether both instructions have the same opcode,
+// regardless of their PCs and arguments.
+func (instr Instruction) EqualIgnoringArg(o Instruction) bool { log.DebugLog()
+	return instr.Op == o.Op
+}
+
+// DefaultPCWidth is the number of digits PCs are zero-padded to, which fits
+// any code within the contract size limit.
+const DefaultPCWidth = 6
+
+// format renders the instruction like String, zero-padding the PC to the given
+// number of digits.
+func (instr Instruction) format(width int) string { log.DebugLog()
+	return instr.formatWith(instr.formatPC(width), opString(instr.Op))
+}
+
+// formatPC renders the PC zero-padded to the given number of digits.
+func (instr Instruction) formatPC(width int) string { log.DebugLog()
+	return fmt.Sprintf("%0*d", width, instr.PC)
+}
+
+// formatWith renders the instruction like format, but with the given PC
+// column and mnemonic, for variants annotating or restyling either.
+func (instr Instruction) formatWith(pc, mnemonic string) string { log.DebugLog()
+	if instr.Arg != nil && 0 < len(instr.Arg) {
+		return fmt.Sprintf("%s: %s 0x%x", pc, mnemonic, instr.Arg)
+	}
+	return fmt.Sprintf("%s: %s", pc, mnemonic)
+}
+
 // Iterator for disassembled EVM instructions
 type instructionIterator struct {
 	code    []byte
@@ -33,15 +95,52 @@ type instructionIterator struct {
 	op      vm.OpCode
 	error   error
 	started bool
+	fork    Fork
+	lenient bool
 }
 
-// Create a new instruction iterator.
+// Create a new instruction iterator for the latest fork.
 func NewInstructionIterator(code []byte) *instructionIterator { log.DebugLog()
+	return NewInstructionIteratorWithFork(code, Latest)
+}
+
+// Create a new instruction iterator decoding and validating opcodes according
+// to the rules of the given fork.
+func NewInstructionIteratorWithFork(code []byte, fork Fork) *instructionIterator { log.DebugLog()
 	it := new(instructionIterator)
 	it.code = code
+	it.fork = fork
+	return it
+}
+
+// Create a new instruction iterator for the latest fork, selecting how a push
+// running off the end of the code is reported. In strict mode, which is what
+// NewInstructionIterator uses, Next fails and Error returns the problem. In
+// lenient mode, the truncated push is yielded as a final instruction with the
+// remaining bytes as its argument and Error stays nil.
+func NewInstructionIteratorStrict(code []byte, lenient bool) *instructionIterator { log.DebugLog()
+	it := NewInstructionIterator(code)
+	it.lenient = lenient
 	return it
 }
 
+// Rewinds the iterator to the beginning of the code, clearing any
+// previously encountered error.
+func (it *instructionIterator) Reset() { log.DebugLog()
+	it.pc = 0
+	it.arg = nil
+	it.op = 0
+	it.error = nil
+	it.started = false
+}
+
+// Returns a copy of the iterator sharing the same code. Advancing the copy
+// does not affect the original and vice versa.
+func (it *instructionIterator) Clone() *instructionIterator { log.DebugLog()
+	cpy := *it
+	return &cpy
+}
+
 // Returns true if there is a next instruction and moves on.
 func (it *instructionIterator) Next() bool { log.DebugLog()
 	if it.error != nil || uint64(len(it.code)) <= it.pc {
@@ -51,10 +150,7 @@ func (it *instructionIterator) Next() bool { log.DebugLog()
 
 	if it.started {
 		// Since the iteration has been already started we move to the next instruction.
-		if it.arg != nil {
-			it.pc += uint64(len(it.arg))
-		}
-		it.pc++
+		it.pc = it.nextPC()
 	} else {
 		// We start the iteration from the first instruction.
 		it.started = true
@@ -65,22 +161,129 @@ func (it *instructionIterator) Next() bool { log.DebugLog()
 		return false
 	}
 
-	it.op = vm.OpCode(it.code[it.pc])
-	if it.op.IsPush() {
-		a := uint64(it.op) - uint64(vm.PUSH1) + 1
-		u := it.pc + 1 + a
-		if uint64(len(it.code)) <= it.pc || uint64(len(it.code)) < u {
-			it.error = fmt.Errorf("incomplete push instruction at %v", it.pc)
-			return false
+	it.op, it.arg, it.error = decodeInstruction(it.code, it.pc, it.fork)
+	if it.lenient {
+		it.error = nil
+	}
+	return it.error == nil
+}
+
+// Returns whether Next has been called since the iterator was created or
+// last reset.
+func (it *instructionIterator) Started() bool { log.DebugLog()
+	return it.started
+}
+
+// Returns whether the iteration is over, either because the end of the code
+// was reached or because an error was encountered.
+func (it *instructionIterator) Done() bool { log.DebugLog()
+	return it.error != nil || uint64(len(it.code)) <= it.pc
+}
+
+// Advances the iterator to the next instruction whose opcode is one of ops.
+// Returns false if the end of the code or an error was reached first.
+func (it *instructionIterator) NextMatching(ops ...vm.OpCode) bool { log.DebugLog()
+	for it.Next() {
+		for _, op := range ops {
+			if it.op == op {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Advances the iterator to the next JUMPDEST, skipping push data. Returns
+// false if the end of the code or an error was reached first.
+func (it *instructionIterator) NextJumpDest() bool { log.DebugLog()
+	return it.NextMatching(vm.JUMPDEST)
+}
+
+// Positions the iterator so that the next call to Next decodes the
+// instruction at pc. An error is returned if pc is beyond the code or does
+// not start an instruction, in which case the iterator is left untouched.
+func (it *instructionIterator) Seek(pc uint64) error { log.DebugLog()
+	if uint64(len(it.code)) <= pc {
+		return fmt.Errorf("seek beyond code: %v >= %v", pc, len(it.code))
+	}
+	if !it.isBoundary(pc) {
+		return fmt.Errorf("seek into push data at %v", pc)
+	}
+	it.Reset()
+	it.pc = pc
+	return nil
+}
+
+// isBoundary returns whether an instruction starts at pc, or pc is where the
+// iteration ends after the last instruction, scanning the code from the start
+// with the same fork and leniency as the iterator.
+func (it *instructionIterator) isBoundary(pc uint64) bool { log.DebugLog()
+	pcs, end, _ := instructionBoundaries(it.code, it.fork, it.lenient)
+	_, ok := searchBoundary(pcs, end, pc)
+	return ok
+}
+
+// Returns the opcode and argument of the instruction following the current
+// one without advancing the iterator. The bool is false if there is no such
+// instruction or it cannot be decoded.
+func (it *instructionIterator) Peek() (vm.OpCode, []byte, bool) { log.DebugLog()
+	if it.error != nil {
+		return 0, nil, false
+	}
+	pc := it.pc
+	if it.started {
+		pc = it.nextPC()
+	}
+	if uint64(len(it.code)) <= pc {
+		return 0, nil, false
+	}
+	op, arg, err := decodeInstruction(it.code, pc, it.fork)
+	if err != nil {
+		return 0, nil, false
+	}
+	return op, arg, true
+}
+
+// nextPC returns the offset right after the current instruction.
+func (it *instructionIterator) nextPC() uint64 { log.DebugLog()
+	return it.pc + it.Size()
+}
+
+// decodeInstruction decodes the instruction at pc, which must be within the
+// code, according to the rules of fork. If a push runs off the end of the
+// code, its partial argument is returned alongside the error.
+func decodeInstruction(code []byte, pc uint64, fork Fork) (vm.OpCode, []byte, error) { log.DebugLog()
+	op := vm.OpCode(code[pc])
+	switch {
+	case op == vm.PUSH0 && fork.defines(op):
+		// PUSH0 (EIP-3855) pushes a constant zero and has no immediate bytes.
+		return op, []byte{}, nil
+	case op.IsPush():
+		a := uint64(op) - uint64(vm.PUSH1) + 1
+		u := pc + 1 + a
+		if uint64(len(code)) < u {
+			return op, code[pc+1:], &ErrTruncatedPush{PC: pc, Op: op, Need: int(a), Have: len(code) - int(pc) - 1}
 		}
-		it.arg = it.code[it.pc+1 : u]
-	} else {
-		it.arg = nil
+		return op, code[pc+1 : u], nil
 	}
-	return true
+	return op, nil, nil
+}
+
+// ErrTruncatedPush is returned when a push instruction runs off the end of the
+// code before all of its immediate bytes are available.
+type ErrTruncatedPush struct {
+	PC   uint64    // Offset of the push instruction
+	Op   vm.OpCode // Push opcode
+	Need int       // Number of immediate bytes required
+	Have int       // Number of immediate bytes available
+}
+
+func (e *ErrTruncatedPush) Error() string { log.DebugLog()
+	return fmt.Sprintf("incomplete PUSH%d at pc %d: need %d bytes, have %d", e.Need, e.PC, e.Need, e.Have)
 }
 
-// Returns any error that may have been encountered.
+// Returns any error that may have been encountered. If the error is non-nil,
+// Op and Arg describe the final, partial instruction that failed to decode.
 func (it *instructionIterator) Error() error { log.DebugLog()
 	return it.error
 }
@@ -95,43 +298,537 @@ func (it *instructionIterator) Op() vm.OpCode { log.DebugLog()
 	return it.op
 }
 
-// Returns the argument of the current instruction.
+// Returns whether the current instruction unconditionally ends the execution
+// of its basic block, i.e. halts or jumps. JUMPI falls through and is not a
+// terminator.
+func (it *instructionIterator) IsTerminator() bool { log.DebugLog()
+	switch it.op {
+	case vm.STOP, vm.RETURN, vm.REVERT, vm.SELFDESTRUCT, vm.INVALID, vm.JUMP:
+		return true
+	}
+	return false
+}
+
+// Returns the raw opcode byte at the current position, independent of the
+// opcode definitions in core/vm. Zero is returned past the end of the code.
+func (it *instructionIterator) Byte() byte { log.DebugLog()
+	if uint64(len(it.code)) <= it.pc {
+		return 0
+	}
+	return it.code[it.pc]
+}
+
+// Returns the mnemonic of the current instruction. Opcodes unknown to the
+// EVM are rendered as "opcode 0xNN".
+func (it *instructionIterator) OpString() string { log.DebugLog()
+	return opString(it.op)
+}
+
+// Returns whether the current opcode is defined by the EVM in the iterator's
+// fork. The designated INVALID opcode (0xfe) counts as defined.
+func (it *instructionIterator) IsValidOp() bool { log.DebugLog()
+	return it.fork.defines(it.op)
+}
+
+// isDefined returns whether op is a known EVM instruction.
+func isDefined(op vm.OpCode) bool { log.DebugLog()
+	return vm.StringToOp(op.String()) == op
+}
+
+// opString returns the mnemonic of op, or "opcode 0xNN" if op is undefined.
+func opString(op vm.OpCode) string { log.DebugLog()
+	if isDefined(op) {
+		return op.String()
+	}
+	return fmt.Sprintf("opcode 0x%02x", byte(op))
+}
+
+// Returns the code from the current instruction onwards, or an empty slice
+// once the end has been reached. The slice aliases the code.
+func (it *instructionIterator) Remaining() []byte { log.DebugLog()
+	if uint64(len(it.code)) <= it.pc {
+		return []byte{}
+	}
+	return it.code[it.pc:]
+}
+
+// Returns the number of bytes the current instruction occupies in the code,
+// i.e. the opcode plus its immediate argument.
+func (it *instructionIterator) Size() uint64 { log.DebugLog()
+	return 1 + uint64(len(it.arg))
+}
+
+// Returns the argument of the current instruction. The slice aliases the
+// underlying code, so it changes if the code buffer is modified or reused.
+// Use ArgCopy to retain the argument safely.
 func (it *instructionIterator) Arg() []byte { log.DebugLog()
 	return it.arg
 }
 
+// Returns a freshly allocated copy of the argument of the current instruction.
+func (it *instructionIterator) ArgCopy() []byte { log.DebugLog()
+	return common.CopyBytes(it.arg)
+}
+
+// Returns the argument of the current instruction as a big-endian unsigned
+// integer, or nil if the instruction has no argument. A new value is
+// allocated on every call.
+func (it *instructionIterator) ArgBig() *big.Int { log.DebugLog()
+	if len(it.arg) == 0 {
+		return nil
+	}
+	return new(big.Int).SetBytes(it.arg)
+}
+
+// Returns the argument of the current instruction as a two's complement signed
+// integer, sign-extending from the top bit of the pushed width, or nil if the
+// instruction has no argument.
+func (it *instructionIterator) ArgBigSigned() *big.Int { log.DebugLog()
+	value := it.ArgBig()
+	if value != nil && it.arg[0]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), uint(8*len(it.arg))))
+	}
+	return value
+}
+
+// Returns the location of the argument of the current instruction within the
+// code, so callers can slice it only when needed. The offset is the byte after
+// the opcode; the length is zero for instructions without argument.
+func (it *instructionIterator) ArgSpan() (off uint64, length int) { log.DebugLog()
+	return it.pc + 1, len(it.arg)
+}
+
+// Returns the argument of the current instruction as lowercase hex without a
+// 0x prefix, or an empty string if the instruction has no argument.
+func (it *instructionIterator) ArgHex() string { log.DebugLog()
+	return hex.EncodeToString(it.arg)
+}
+
+// Returns the argument of the current instruction as an address if the
+// instruction is a PUSH20.
+func (it *instructionIterator) ArgAddress() (common.Address, bool) { log.DebugLog()
+	if it.op != vm.PUSH20 || len(it.arg) != common.AddressLength {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(it.arg), true
+}
+
+// Returns the current instruction. The argument is copied, so the returned
+// value remains valid after the iterator moves on.
+func (it *instructionIterator) Instruction() Instruction { log.DebugLog()
+	return Instruction{
+		PC:  it.pc,
+		Op:  it.op,
+		Arg: it.ArgCopy(),
+	}
+}
+
+// current returns the current instruction with its argument aliasing the code.
+func (it *instructionIterator) current() Instruction { log.DebugLog()
+	return Instruction{PC: it.pc, Op: it.op, Arg: it.arg}
+}
+
 // Pretty-print all disassembled EVM instructions to stdout.
 func PrintDisassembled(code string) error { log.DebugLog()
-	script, err := hex.DecodeString(code)
+	return FprintDisassembled(os.Stdout, code)
+}
+
+// Pretty-print all disassembled EVM instructions to the given writer.
+func FprintDisassembled(w io.Writer, code string) error { log.DebugLog()
+	script, err := decodeHex(code)
 	if err != nil {
 		return err
 	}
 
 	it := NewInstructionIterator(script)
 	for it.Next() {
-		if it.Arg() != nil && 0 < len(it.Arg()) {
-			fmt.Printf("%06v: %v 0x%x\n", it.PC(), it.Op(), it.Arg())
-		} else {
-			fmt.Printf("%06v: %v\n", it.PC(), it.Op())
+		if _, err = fmt.Fprintln(w, it.current()); err != nil {
+			return err
 		}
 	}
 	return it.Error()
 }
 
-// Return all disassembled EVM instructions in human-readable format.
+// ErrOutputLimit is returned by FprintDisassembledLimit when the output was
+// cut short to stay within the byte budget.
+var ErrOutputLimit = errors.New("output limit reached")
+
+// Pretty-print disassembled EVM instructions to the given writer until the
+// next line would exceed maxBytes in total. Only whole lines are written. If
+// output was cut short, the bytes written so far are returned together with
+// ErrOutputLimit.
+func FprintDisassembledLimit(w io.Writer, code []byte, maxBytes int) (written int, err error) { log.DebugLog()
+	it := NewInstructionIterator(code)
+	for it.Next() {
+		line := DefaultFormat(it.current())
+		if written+len(line) > maxBytes {
+			return written, ErrOutputLimit
+		}
+		n, err := io.WriteString(w, line)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, it.Error()
+}
+
+// Return all disassembled EVM instructions in human-readable format. Every
+// element ends with a newline, use DisassembleLines for unterminated lines.
+// Empty code yields no instructions and no error, use DisassembleStrict to
+// tell it apart.
 func Disassemble(script []byte) ([]string, error) { log.DebugLog()
-	instrs := make([]string, 0)
+	return DisassembleFormat(script, DefaultFormat)
+}
+
+// ErrEmptyCode is returned by DisassembleStrict for zero-length code.
+var ErrEmptyCode = errors.New("empty code")
+
+// Return all disassembled EVM instructions in human-readable format like
+// Disassemble, but fail with ErrEmptyCode if there is no code at all.
+func DisassembleStrict(script []byte) ([]string, error) { log.DebugLog()
+	if len(script) == 0 {
+		return nil, ErrEmptyCode
+	}
+	return Disassemble(script)
+}
+
+// Return all disassembled EVM instructions, each rendered by the given format
+// function. The argument of the passed instruction aliases the code and must
+// not be retained by format.
+func DisassembleFormat(script []byte, format func(Instruction) string) ([]string, error) { log.DebugLog()
+	instrs := make([]string, 0, len(script)/2)
 
 	it := NewInstructionIterator(script)
 	for it.Next() {
-		if it.Arg() != nil && 0 < len(it.Arg()) {
-			instrs = append(instrs, fmt.Sprintf("%06v: %v 0x%x\n", it.PC(), it.Op(), it.Arg()))
-		} else {
-			instrs = append(instrs, fmt.Sprintf("%06v: %v\n", it.PC(), it.Op()))
+		instrs = append(instrs, format(it.current()))
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
+
+// DefaultFormat renders an instruction the way Disassemble does, including
+// the trailing newline.
+func DefaultFormat(instr Instruction) string { log.DebugLog()
+	return instr.String() + "\n"
+}
+
+// Return all disassembled EVM instructions in human-readable format, prefixed
+// with their zero-based index, e.g. "[0042] 000123: DUP1".
+func DisassembleIndexed(script []byte) ([]string, error) { log.DebugLog()
+	index := 0
+	return DisassembleFormat(script, func(instr Instruction) string {
+		line := fmt.Sprintf("[%04d] %v\n", index, instr)
+		index++
+		return line
+	})
+}
+
+// Return all disassembled EVM instructions in human-readable format with
+// lowercase mnemonics, e.g. "push1 0x60". Arguments are left untouched.
+func DisassembleLower(script []byte) ([]string, error) { log.DebugLog()
+	return DisassembleFormat(script, func(instr Instruction) string {
+		return instr.formatWith(instr.formatPC(DefaultPCWidth), strings.ToLower(opString(instr.Op))) + "\n"
+	})
+}
+
+// Return all disassembled EVM instructions in human-readable format without
+// trailing newlines, so the lines can be joined with any separator or fed
+// straight into Assemble.
+func DisassembleLines(script []byte) ([]string, error) { log.DebugLog()
+	instrs := make([]string, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		instrs = append(instrs, it.current().String())
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
+
+// Return all disassembled EVM instructions in human-readable format, starting
+// the decoding at the given offset. PCs remain relative to the start of the
+// code. An offset beyond the code yields no instructions.
+func DisassembleFrom(script []byte, start uint64) ([]string, error) { log.DebugLog()
+	instrs := make([]string, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	it.pc = start
+	for it.Next() {
+		instrs = append(instrs, DefaultFormat(it.current()))
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
+
+// Return the disassembled EVM instructions in human-readable format within the
+// window [start, end) of the code. PCs remain relative to the start of the
+// code. A push whose argument crosses end is reported as truncated, with the
+// instructions before it returned alongside the error.
+func DisassembleRange(script []byte, start, end uint64) ([]string, error) { log.DebugLog()
+	if start > end || end > uint64(len(script)) {
+		return nil, fmt.Errorf("invalid range [%v, %v) for code of length %v", start, end, len(script))
+	}
+	instrs := make([]string, 0, (end-start)/2)
+
+	it := NewInstructionIterator(script[:end])
+	it.pc = start
+	for it.Next() {
+		instrs = append(instrs, DefaultFormat(it.current()))
+	}
+	return instrs, it.Error()
+}
+
+// Number of instructions decoded between two checks of the context in
+// DisassembleContext.
+const contextCheckInterval = 4096
+
+// Return all disassembled EVM instructions in human-readable format, aborting
+// if the context is cancelled. The instructions decoded up to the point of
+// cancellation or a decoding failure are returned together with the error.
+func DisassembleContext(ctx context.Context, script []byte) ([]string, error) { log.DebugLog()
+	instrs := make([]string, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for cnt := 0; ; cnt++ {
+		if cnt%contextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return instrs, err
+			}
+		}
+		if !it.Next() {
+			break
+		}
+		instrs = append(instrs, DefaultFormat(it.current()))
+	}
+	return instrs, it.Error()
+}
+
+// ErrTooManyInstructions is returned by DisassembleLimit when the code holds
+// more instructions than allowed.
+var ErrTooManyInstructions = errors.New("too many instructions")
+
+// Return at most max disassembled EVM instructions in human-readable format.
+// If the code holds more, the first max instructions are returned together
+// with ErrTooManyInstructions.
+func DisassembleLimit(script []byte, max int) ([]string, error) { log.DebugLog()
+	size := len(script) / 2
+	if max < size {
+		size = max
+	}
+	if size < 0 {
+		size = 0
+	}
+	instrs := make([]string, 0, size)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if len(instrs) >= max {
+			return instrs, ErrTooManyInstructions
 		}
+		instrs = append(instrs, DefaultFormat(it.current()))
 	}
 	if err := it.Error(); err != nil {
 		return nil, err
 	}
 	return instrs, nil
 }
+
+// Walk calls fn for every instruction in the code, stopping at the first
+// error returned by fn, which is passed back to the caller. The arg slice
+// aliases the code and is only valid during the call.
+func Walk(script []byte, fn func(pc uint64, op vm.OpCode, arg []byte) error) error { log.DebugLog()
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if err := fn(it.PC(), it.Op(), it.Arg()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// Return all disassembled EVM instructions in human-readable format from a hex
+// string. Surrounding whitespace and a 0x prefix are ignored.
+func DisassembleHex(code string) ([]string, error) { log.DebugLog()
+	script, err := decodeHex(code)
+	if err != nil {
+		return nil, err
+	}
+	return Disassemble(script)
+}
+
+// decodeHex decodes a hex string with optional surrounding whitespace and 0x
+// prefix. Invalid characters are reported with their position in the input.
+func decodeHex(code string) ([]byte, error) { log.DebugLog()
+	offset := len(code) - len(strings.TrimLeftFunc(code, unicode.IsSpace))
+	code = strings.TrimSpace(code)
+	if strings.HasPrefix(code, "0x") || strings.HasPrefix(code, "0X") {
+		code = code[2:]
+		offset += 2
+	}
+	script, err := hex.DecodeString(code)
+	var invalid hex.InvalidByteError
+	if errors.As(err, &invalid) {
+		pos := offset + strings.IndexByte(code, byte(invalid))
+		return nil, fmt.Errorf("invalid bytecode hex at position %d: %w", pos, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytecode hex: %w", err)
+	}
+	return script, nil
+}
+
+// Return all disassembled EVM instructions in structured format.
+func DisassembleInstructions(script []byte) ([]Instruction, error) { log.DebugLog()
+	instrs := make([]Instruction, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		instrs = append(instrs, it.Instruction())
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
+
+// RangedLine is a disassembled instruction in human-readable format, without
+// trailing newline, together with the range of code bytes it was decoded from.
+type RangedLine struct {
+	Text      string
+	StartByte uint64 // Offset of the opcode
+	EndByte   uint64 // Offset after the last argument byte
+}
+
+// Return all disassembled EVM instructions in human-readable format, tagged
+// with the bytes of the code they occupy.
+func DisassembleWithRanges(script []byte) ([]RangedLine, error) { log.DebugLog()
+	lines := make([]RangedLine, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		lines = append(lines, RangedLine{Text: it.current().String(), StartByte: it.PC(), EndByte: it.nextPC()})
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// jsonInstruction is the JSON representation of a disassembled instruction.
+type jsonInstruction struct {
+	PC     uint64        `json:"pc"`
+	Op     string        `json:"op"`
+	Opcode byte          `json:"opcode"`
+	Arg    hexutil.Bytes `json:"arg,omitempty"`
+}
+
+// Return all disassembled EVM instructions as a JSON array. If the code is
+// truncated, the instructions decoded up to that point are returned together
+// with the error.
+func DisassembleJSON(script []byte) ([]byte, error) { log.DebugLog()
+	instrs := make([]jsonInstruction, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		instrs = append(instrs, jsonInstruction{
+			PC:     it.PC(),
+			Op:     it.OpString(),
+			Opcode: byte(it.Op()),
+			Arg:    it.Arg(),
+		})
+	}
+	out, err := json.Marshal(instrs)
+	if err != nil {
+		return nil, err
+	}
+	return out, it.Error()
+}
+
+// Write all disassembled EVM instructions to w as CSV, one row per instruction
+// after a header row. Opcode and argument are bare lowercase hex. If the code
+// is truncated, the rows decoded up to that point are written before the
+// error is returned.
+func DisassembleCSV(w io.Writer, script []byte) error { log.DebugLog()
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"pc", "index", "opcode_hex", "mnemonic", "arg_hex"}); err != nil {
+		return err
+	}
+	it := NewInstructionIterator(script)
+	for index := 0; it.Next(); index++ {
+		row := []string{
+			strconv.FormatUint(it.PC(), 10),
+			strconv.Itoa(index),
+			fmt.Sprintf("%02x", byte(it.Op())),
+			it.OpString(),
+			it.ArgHex(),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return err
+	}
+	return it.Error()
+}
+
+// Return the number of successfully decoded EVM instructions without
+// formatting them.
+func CountInstructions(script []byte) (int, error) { log.DebugLog()
+	cnt := 0
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		cnt++
+	}
+	return cnt, it.Error()
+}
+
+// Validate checks that the code is well-formed, i.e. that every push
+// instruction is followed by its complete immediate data.
+func Validate(script []byte) error { log.DebugLog()
+	it := NewInstructionIterator(script)
+	for it.Next() {
+	}
+	return it.Error()
+}
+
+// Return the instruction immediately preceding the one starting at pc. The pc
+// must be an instruction boundary or the end of the code.
+func PrevInstruction(script []byte, pc uint64) (Instruction, error) { log.DebugLog()
+	if pc == 0 {
+		return Instruction{}, errors.New("no instruction before pc 0")
+	}
+	pcs, end, err := instructionBoundaries(script, Latest, false)
+	i, ok := searchBoundary(pcs, end, pc)
+	if !ok {
+		if err != nil && pc > end {
+			return Instruction{}, err
+		}
+		return Instruction{}, fmt.Errorf("pc %v is not an instruction boundary", pc)
+	}
+	op, arg, _ := decodeInstruction(script, pcs[i-1], Latest)
+	return Instruction{PC: pcs[i-1], Op: op, Arg: common.CopyBytes(arg)}, nil
+}
+
+// Return the opcode and argument of the instruction starting at pc. The pc
+// must be an instruction boundary within the code, not inside push data.
+func OpAt(script []byte, pc uint64) (vm.OpCode, []byte, error) { log.DebugLog()
+	if pc >= uint64(len(script)) {
+		return 0, nil, fmt.Errorf("pc %v out of range", pc)
+	}
+	pcs, end, _ := instructionBoundaries(script, Latest, false)
+	if _, ok := searchBoundary(pcs, end, pc); !ok {
+		return 0, nil, fmt.Errorf("pc %v is inside push data", pc)
+	}
+	op, arg, err := decodeInstruction(script, pc, Latest)
+	return op, common.CopyBytes(arg), err
+}
@@ -17,9 +17,20 @@
 package asm
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
 	"testing"
 
 	"encoding/hex"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // Tests disassembling the instructions for valid evm code
@@ -53,6 +64,33 @@ func TestInstructionIteratorInvalid(t *testing.T) { log.DebugLog()
 	if it.Error() == nil {
 		t.Errorf("Expected an error, but got %v instead.", cnt)
 	}
+	if it.Op() != vm.PUSH2 || !bytes.Equal(it.Arg(), []byte{0x00}) {
+		t.Errorf("Expected partial PUSH2 0x00, but got %v %x instead.", it.Op(), it.Arg())
+	}
+}
+
+// Tests that lenient iteration yields the truncated push instead of failing
+func TestInstructionIteratorLenient(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("01620102")
+
+	var instrs []Instruction
+	it := NewInstructionIteratorStrict(script, true)
+	for it.Next() {
+		instrs = append(instrs, it.Instruction())
+	}
+	if err := it.Error(); err != nil {
+		t.Errorf("Expected no error, but encountered %v instead.", err)
+	}
+	if len(instrs) != 2 || instrs[1].Op != vm.PUSH3 || !bytes.Equal(instrs[1].Arg, []byte{0x01, 0x02}) {
+		t.Errorf("Expected a final partial PUSH3 0x0102, but got %+v.", instrs)
+	}
+
+	it = NewInstructionIteratorStrict(script, false)
+	for it.Next() {
+	}
+	if it.Error() == nil {
+		t.Errorf("Expected an error in strict mode, but got none.")
+	}
 }
 
 // Tests disassembling the instructions for empty evm code
@@ -72,3 +110,950 @@ func TestInstructionIteratorEmpty(t *testing.T) { log.DebugLog()
 		t.Errorf("Expected 0, but got %v instead.", cnt)
 	}
 }
+
+// Tests that PUSH0 carries no immediate and the following opcode is decoded
+func TestInstructionIteratorPush0(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("5f6001015f")
+
+	instrs, err := DisassembleInstructions(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []Instruction{
+		{PC: 0, Op: vm.PUSH0, Arg: []byte{}},
+		{PC: 1, Op: vm.PUSH1, Arg: []byte{0x01}},
+		{PC: 3, Op: vm.ADD},
+		{PC: 4, Op: vm.PUSH0, Arg: []byte{}},
+	}
+	if len(instrs) != len(want) {
+		t.Fatalf("Expected %d instructions, but got %d instead.", len(want), len(instrs))
+	}
+	for i := range want {
+		if instrs[i].PC != want[i].PC || instrs[i].Op != want[i].Op || !bytes.Equal(instrs[i].Arg, want[i].Arg) {
+			t.Errorf("instruction %d: expected %+v, but got %+v instead.", i, want[i], instrs[i])
+		}
+	}
+	if instrs[0].Arg == nil || len(instrs[0].Arg) != 0 {
+		t.Errorf("Expected empty non-nil argument for PUSH0, but got %#v instead.", instrs[0].Arg)
+	}
+}
+
+// Tests that peeking reports the next instruction without consuming it
+func TestInstructionIteratorPeek(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("60aa0161")
+
+	it := NewInstructionIterator(script)
+	if op, arg, ok := it.Peek(); !ok || op != vm.PUSH1 || !bytes.Equal(arg, []byte{0xaa}) {
+		t.Errorf("Expected PUSH1 0xaa, but got %v %x (%v) instead.", op, arg, ok)
+	}
+	if !it.Next() || it.Op() != vm.PUSH1 {
+		t.Fatalf("Expected PUSH1 after peek, but got %v instead.", it.Op())
+	}
+	if op, _, ok := it.Peek(); !ok || op != vm.ADD {
+		t.Errorf("Expected ADD, but got %v (%v) instead.", op, ok)
+	}
+	if it.PC() != 0 || it.Op() != vm.PUSH1 {
+		t.Errorf("Expected peek to leave the iterator at PUSH1, but got %v at %v.", it.Op(), it.PC())
+	}
+	it.Next()
+	// Peeking at the truncated push must not record an error
+	if _, _, ok := it.Peek(); ok {
+		t.Errorf("Expected no instruction after ADD, but got one.")
+	}
+	if err := it.Error(); err != nil {
+		t.Errorf("Expected no error after peek, but got %v instead.", err)
+	}
+}
+
+// Tests that iteration can be restricted to a set of opcodes
+func TestInstructionIteratorNextMatching(t *testing.T) { log.DebugLog()
+	// CALL PUSH1 0xf4 DELEGATECALL ADD STATICCALL PUSH1 (truncated)
+	script, _ := hex.DecodeString("f160f4f401fa60")
+
+	it := NewInstructionIterator(script)
+	var pcs []uint64
+	for it.NextMatching(vm.CALL, vm.DELEGATECALL, vm.STATICCALL, vm.CALLCODE) {
+		pcs = append(pcs, it.PC())
+	}
+	if want := []uint64{0, 3, 5}; !reflect.DeepEqual(pcs, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, pcs)
+	}
+	if it.Error() == nil {
+		t.Errorf("Expected an error, but got none.")
+	}
+}
+
+// Tests advancing to jump destinations without stopping in push data
+func TestInstructionIteratorNextJumpDest(t *testing.T) { log.DebugLog()
+	// PUSH2 0x5b5b JUMPDEST ADD PUSH1 0x5b JUMPDEST
+	script, _ := hex.DecodeString("615b5b5b01605b5b")
+
+	it := NewInstructionIterator(script)
+	var pcs []uint64
+	for it.NextJumpDest() {
+		if it.Op() != vm.JUMPDEST {
+			t.Errorf("Expected JUMPDEST, but got %v at %d.", it.Op(), it.PC())
+		}
+		pcs = append(pcs, it.PC())
+	}
+	if want := []uint64{3, 7}; !reflect.DeepEqual(pcs, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, pcs)
+	}
+}
+
+// Tests seeking to instruction boundaries and rejecting push data
+func TestInstructionIteratorSeek(t *testing.T) { log.DebugLog()
+	// PUSH2 0x5b5b JUMPDEST ADD PUSH1 (truncated)
+	script, _ := hex.DecodeString("615b5b5b0160")
+
+	it := NewInstructionIterator(script)
+	if err := it.Seek(3); err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if op, _, ok := it.Peek(); !ok || op != vm.JUMPDEST {
+		t.Errorf("Expected to peek JUMPDEST after seeking, but got %v (%v).", op, ok)
+	}
+	if !it.Next() || it.PC() != 3 || it.Op() != vm.JUMPDEST {
+		t.Errorf("Expected JUMPDEST at 3, but got %v at %v.", it.Op(), it.PC())
+	}
+	if !it.Next() || it.PC() != 4 || it.Op() != vm.ADD {
+		t.Errorf("Expected ADD at 4, but got %v at %v.", it.Op(), it.PC())
+	}
+	for _, pc := range []uint64{1, 2, 6, 100} {
+		if err := it.Seek(pc); err == nil {
+			t.Errorf("Expected an error seeking to %v, but got none.", pc)
+		}
+	}
+	if err := it.Seek(5); err != nil {
+		t.Errorf("Expected no error seeking to truncated push, but encountered %v instead.", err)
+	}
+	if it.Next() || it.Error() == nil {
+		t.Errorf("Expected an error decoding the truncated push, but got none.")
+	}
+}
+
+// Tests the rendering of defined and undefined opcodes
+func TestInstructionIteratorOpString(t *testing.T) { log.DebugLog()
+	// STOP PUSH1 0xb0 (pseudo PUSH) 0x0c SELFDESTRUCT
+	script, _ := hex.DecodeString("0060b0b00cff")
+
+	var names []string
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		names = append(names, it.OpString())
+	}
+	want := []string{"STOP", "PUSH1", "opcode 0xb0", "opcode 0x0c", "SELFDESTRUCT"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, names)
+	}
+}
+
+// Tests the encoded size of instructions
+func TestInstructionIteratorSize(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6160aa015f7f" + strings.Repeat("00", 32))
+
+	var sizes []uint64
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		if next := it.PC() + it.Size(); next < uint64(len(script)) {
+			if op, _, ok := it.Peek(); !ok || op != vm.OpCode(script[next]) {
+				t.Errorf("Expected next instruction at %d, but peeked %v.", next, op)
+			}
+		}
+		sizes = append(sizes, it.Size())
+	}
+	if want := []uint64{3, 1, 1, 33}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, sizes)
+	}
+}
+
+// Tests access to the undecoded remainder of the code
+func TestInstructionIteratorRemaining(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6080fe0102")
+
+	it := NewInstructionIterator(script)
+	for it.Next() && it.Op() != vm.INVALID {
+	}
+	if rest := it.Remaining(); !bytes.Equal(rest, script[2:]) {
+		t.Errorf("Expected %x, but got %x instead.", script[2:], rest)
+	}
+	for it.Next() {
+	}
+	if rest := it.Remaining(); rest == nil || len(rest) != 0 {
+		t.Errorf("Expected an empty remainder, but got %#v instead.", rest)
+	}
+}
+
+// Tests that copied arguments do not alias the code
+func TestInstructionIteratorArgCopy(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6160aa")
+
+	it := NewInstructionIterator(script)
+	it.Next()
+	arg, cpy := it.Arg(), it.ArgCopy()
+	script[1] = 0xff
+	if arg[0] != 0xff {
+		t.Errorf("Expected Arg to alias the code, but got %x.", arg)
+	}
+	if !bytes.Equal(cpy, []byte{0x60, 0xaa}) {
+		t.Errorf("Expected 60aa, but got %x instead.", cpy)
+	}
+}
+
+// Tests that a cloned iterator advances independently
+func TestInstructionIteratorClone(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6080015b00")
+
+	it := NewInstructionIterator(script)
+	it.Next()
+	cpy := it.Clone()
+	cpy.Next()
+	cpy.Next()
+	if it.PC() != 0 || it.Op() != vm.PUSH1 {
+		t.Errorf("Expected original at PUSH1, but got %v at %v.", it.Op(), it.PC())
+	}
+	if !it.Next() || it.Op() != vm.ADD {
+		t.Errorf("Expected ADD after PUSH1, but got %v.", it.Op())
+	}
+	if cpy.PC() != 3 || cpy.Op() != vm.JUMPDEST {
+		t.Errorf("Expected clone at JUMPDEST, but got %v at %v.", cpy.Op(), cpy.PC())
+	}
+}
+
+// Tests that a reset iterator walks the code again from the start
+func TestInstructionIteratorReset(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("61000000")
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+	}
+	it.Reset()
+
+	cnt := 0
+	for it.Next() {
+		if cnt == 0 && it.PC() != 0 {
+			t.Errorf("Expected first PC 0 after reset, but got %v instead.", it.PC())
+		}
+		cnt++
+	}
+	if cnt != 2 {
+		t.Errorf("Expected 2, but got %v instead.", cnt)
+	}
+
+	// Resetting must clear a previously recorded error
+	script, _ = hex.DecodeString("6100")
+	it = NewInstructionIterator(script)
+	for it.Next() {
+	}
+	if it.Error() == nil {
+		t.Fatalf("Expected an error, but got none.")
+	}
+	it.Reset()
+	if err := it.Error(); err != nil {
+		t.Errorf("Expected no error after reset, but got %v instead.", err)
+	}
+}
+
+// Tests the big integer interpretation of push arguments
+func TestInstructionIteratorArgBig(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff01")
+
+	it := NewInstructionIterator(script)
+	it.Next()
+	want, _ := new(big.Int).SetString("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 16)
+	if v := it.ArgBig(); v == nil || v.Cmp(want) != 0 {
+		t.Errorf("Expected %x, but got %v instead.", want, v)
+	}
+	it.ArgBig().SetUint64(0)
+	if v := it.ArgBig(); v.Cmp(want) != 0 {
+		t.Errorf("Expected a fresh value, but got %x instead.", v)
+	}
+	it.Next()
+	if v := it.ArgBig(); v != nil {
+		t.Errorf("Expected nil for ADD, but got %v instead.", v)
+	}
+}
+
+// Tests detecting instructions that end a basic block
+func TestInstructionIteratorIsTerminator(t *testing.T) { log.DebugLog()
+	tests := map[vm.OpCode]bool{
+		vm.STOP: true, vm.RETURN: true, vm.REVERT: true, vm.SELFDESTRUCT: true, vm.INVALID: true, vm.JUMP: true,
+		vm.JUMPI: false, vm.JUMPDEST: false, vm.ADD: false, vm.CALL: false,
+	}
+	for op, want := range tests {
+		it := NewInstructionIterator([]byte{byte(op)})
+		it.Next()
+		if got := it.IsTerminator(); got != want {
+			t.Errorf("%v: expected %v, but got %v instead.", op, want, got)
+		}
+	}
+}
+
+// Tests reading the raw opcode byte
+func TestInstructionIteratorByte(t *testing.T) { log.DebugLog()
+	it := NewInstructionIterator([]byte{byte(vm.PUSH1), 0x0c, 0x0c})
+	for _, want := range []byte{0x60, 0x0c} {
+		if !it.Next() {
+			t.Fatalf("Expected instruction, but iterator stopped with %v.", it.Error())
+		}
+		if got := it.Byte(); got != want {
+			t.Errorf("Expected byte 0x%02x, but got 0x%02x instead.", want, got)
+		}
+	}
+	if it.Next() || it.Byte() != 0 {
+		t.Errorf("Expected zero byte past the end, but got 0x%02x.", it.Byte())
+	}
+}
+
+// Tests querying the iteration state without advancing
+func TestInstructionIteratorState(t *testing.T) { log.DebugLog()
+	it := NewInstructionIterator([]byte{byte(vm.PUSH1), 0x01, byte(vm.ADD)})
+	if it.Started() || it.Done() {
+		t.Errorf("Expected fresh iterator, but got started %v, done %v.", it.Started(), it.Done())
+	}
+	it.Next()
+	it.Next()
+	if !it.Started() || it.Done() {
+		t.Errorf("Expected iterator at last instruction, but got started %v, done %v.", it.Started(), it.Done())
+	}
+	it.Next()
+	if !it.Done() {
+		t.Errorf("Expected exhausted iterator.")
+	}
+	it.Reset()
+	if it.Started() || it.Done() {
+		t.Errorf("Expected reset iterator, but got started %v, done %v.", it.Started(), it.Done())
+	}
+
+	it = NewInstructionIterator([]byte{byte(vm.PUSH2), 0x01})
+	it.Next()
+	if !it.Done() {
+		t.Errorf("Expected done after error %v.", it.Error())
+	}
+	if !NewInstructionIterator(nil).Done() {
+		t.Errorf("Expected iterator over empty code to be done.")
+	}
+}
+
+// Tests interpreting the argument as a signed integer
+func TestInstructionIteratorArgBigSigned(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		code string
+		want *big.Int
+	}{
+		{"607f", big.NewInt(127)},
+		{"6080", big.NewInt(-128)},
+		{"61ffff", big.NewInt(-1)},
+		{"617fff", big.NewInt(32767)},
+		{"7f" + strings.Repeat("ff", 32), big.NewInt(-1)},
+		{"01", nil},
+		{"5f", nil},
+	}
+	for _, test := range tests {
+		script, _ := hex.DecodeString(test.code)
+		it := NewInstructionIterator(script)
+		it.Next()
+		if got := it.ArgBigSigned(); (got == nil) != (test.want == nil) || (got != nil && got.Cmp(test.want) != 0) {
+			t.Errorf("code %s: expected %v, but got %v instead.", test.code, test.want, got)
+		}
+	}
+}
+
+// Tests locating the argument within the code
+func TestInstructionIteratorArgSpan(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("0162abcdef5f")
+
+	it := NewInstructionIterator(script)
+	for _, want := range []struct {
+		off    uint64
+		length int
+	}{{1, 0}, {2, 3}, {6, 0}} {
+		if !it.Next() {
+			t.Fatalf("Expected instruction, but iterator stopped with %v.", it.Error())
+		}
+		off, length := it.ArgSpan()
+		if off != want.off || length != want.length {
+			t.Errorf("%v: expected span %d+%d, but got %d+%d.", it.Op(), want.off, want.length, off, length)
+		}
+		if !bytes.Equal(script[off:off+uint64(length)], it.Arg()) {
+			t.Errorf("%v: expected span to hold the argument %x.", it.Op(), it.Arg())
+		}
+	}
+}
+
+// Tests rendering the argument as bare hex
+func TestInstructionIteratorArgHex(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("61ABCD015f")
+
+	it := NewInstructionIterator(script)
+	for _, want := range []string{"abcd", "", ""} {
+		if !it.Next() {
+			t.Fatalf("Expected instruction, but iterator stopped with %v.", it.Error())
+		}
+		if got := it.ArgHex(); got != want {
+			t.Errorf("%v: expected %q, but got %q instead.", it.Op(), want, got)
+		}
+	}
+}
+
+// Tests extracting hardcoded addresses from PUSH20
+func TestInstructionIteratorArgAddress(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("73" + "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed" + "f4" + "7f" + strings.Repeat("11", 32))
+
+	it := NewInstructionIterator(script)
+	it.Next()
+	want := common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	if addr, ok := it.ArgAddress(); !ok || addr != want {
+		t.Errorf("Expected %x, but got %x (%v) instead.", want, addr, ok)
+	}
+	for it.Next() {
+		if _, ok := it.ArgAddress(); ok {
+			t.Errorf("Expected no address for %v, but got one.", it.Op())
+		}
+	}
+}
+
+// Tests that structured instructions carry the decoded fields
+func TestDisassembleInstructions(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6160aa0156")
+
+	instrs, err := DisassembleInstructions(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []Instruction{
+		{PC: 0, Op: vm.PUSH2, Arg: []byte{0x60, 0xaa}},
+		{PC: 3, Op: vm.ADD},
+		{PC: 4, Op: vm.JUMP},
+	}
+	if len(instrs) != len(want) {
+		t.Fatalf("Expected %d instructions, but got %d instead.", len(want), len(instrs))
+	}
+	for i := range want {
+		if instrs[i].PC != want[i].PC || instrs[i].Op != want[i].Op || !bytes.Equal(instrs[i].Arg, want[i].Arg) {
+			t.Errorf("instruction %d: expected %+v, but got %+v instead.", i, want[i], instrs[i])
+		}
+	}
+	// The argument must not alias the original code
+	script[1] = 0xff
+	if instrs[0].Arg[0] != 0x60 {
+		t.Errorf("Expected argument to be a copy, but it changed to %x.", instrs[0].Arg)
+	}
+}
+
+// Tests that the writer based printer emits the disassembly
+func TestFprintDisassembled(t *testing.T) { log.DebugLog()
+	var buf bytes.Buffer
+	if err := FprintDisassembled(&buf, "6160aa01"); err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := "000000: PUSH2 0x60aa\n000003: ADD\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, but got %q instead.", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := FprintDisassembled(&buf, "6100"); err == nil {
+		t.Errorf("Expected an error, but got none.")
+	}
+}
+
+// Tests the JSON disassembly output, including a truncated tail
+func TestDisassembleJSON(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6160aa0161")
+
+	out, err := DisassembleJSON(script)
+	if err == nil {
+		t.Errorf("Expected an error, but got none.")
+	}
+	want := `[{"pc":0,"op":"PUSH2","opcode":97,"arg":"0x60aa"},{"pc":3,"op":"ADD","opcode":1}]`
+	if string(out) != want {
+		t.Errorf("Expected %s, but got %s instead.", want, out)
+	}
+}
+
+// Tests counting instructions for valid and truncated code
+func TestCountInstructions(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		code string
+		cnt  int
+		fail bool
+	}{
+		{"", 0, false},
+		{"61000000", 2, false},
+		{"600101", 2, false},
+		{"600161", 1, true},
+	}
+	for _, test := range tests {
+		script, _ := hex.DecodeString(test.code)
+		cnt, err := CountInstructions(script)
+		if (err != nil) != test.fail {
+			t.Errorf("code %s: expected failure %v, but got error %v.", test.code, test.fail, err)
+		}
+		if cnt != test.cnt {
+			t.Errorf("code %s: expected %d, but got %d instead.", test.code, test.cnt, cnt)
+		}
+	}
+}
+
+// Tests random access to the instruction at a given pc
+func TestOpAt(t *testing.T) { log.DebugLog()
+	// PUSH2 0x5b5b JUMPDEST PUSH1
+	script, _ := hex.DecodeString("615b5b5b60")
+
+	op, arg, err := OpAt(script, 0)
+	if err != nil || op != vm.PUSH2 || !bytes.Equal(arg, []byte{0x5b, 0x5b}) {
+		t.Errorf("pc 0: expected PUSH2 0x5b5b, but got %v 0x%x, error %v.", op, arg, err)
+	}
+	if op, arg, err = OpAt(script, 3); err != nil || op != vm.JUMPDEST || len(arg) != 0 {
+		t.Errorf("pc 3: expected JUMPDEST, but got %v 0x%x, error %v.", op, arg, err)
+	}
+	if op, _, err = OpAt(script, 4); err == nil || op != vm.PUSH1 {
+		t.Errorf("pc 4: expected truncated PUSH1, but got %v, error %v.", op, err)
+	}
+	for _, pc := range []uint64{1, 2, 5, 100} {
+		if _, _, err := OpAt(script, pc); err == nil {
+			t.Errorf("pc %d: expected error, but got none.", pc)
+		}
+	}
+}
+
+// Tests disassembling a window of the code
+func TestDisassembleRange(t *testing.T) { log.DebugLog()
+	// PUSH1 0x01 ADD PUSH2 0x0203 STOP
+	script, _ := hex.DecodeString("600101610203" + "00")
+
+	instrs, err := DisassembleRange(script, 2, 7)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []string{"000002: ADD\n", "000003: PUSH2 0x0203\n", "000006: STOP\n"}; !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+	// A push straddling the end of the window is truncated
+	instrs, err = DisassembleRange(script, 0, 5)
+	var perr *ErrTruncatedPush
+	if !errors.As(err, &perr) || perr.PC != 3 {
+		t.Errorf("Expected truncated push at 3, but got %v instead.", err)
+	}
+	if want := []string{"000000: PUSH1 0x01\n", "000002: ADD\n"}; !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+	if instrs, err := DisassembleRange(script, 3, 3); err != nil || len(instrs) != 0 {
+		t.Errorf("Expected empty window, but got %q (%v).", instrs, err)
+	}
+	for _, r := range [][2]uint64{{4, 3}, {0, 8}} {
+		if _, err := DisassembleRange(script, r[0], r[1]); err == nil {
+			t.Errorf("range %v: expected error, but got none.", r)
+		}
+	}
+}
+
+// Tests capping the number of disassembled instructions
+func TestDisassembleLimit(t *testing.T) { log.DebugLog()
+	// PUSH1 0x01 PUSH1 0x02 ADD
+	script, _ := hex.DecodeString("6001600201")
+
+	instrs, err := DisassembleLimit(script, 2)
+	if err != ErrTooManyInstructions {
+		t.Errorf("Expected ErrTooManyInstructions, but got %v instead.", err)
+	}
+	if want := []string{"000000: PUSH1 0x01\n", "000002: PUSH1 0x02\n"}; !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+	if instrs, err := DisassembleLimit(script, 3); err != nil || len(instrs) != 3 {
+		t.Errorf("Expected 3 instructions, but got %d, error %v.", len(instrs), err)
+	}
+	if _, err := DisassembleLimit([]byte{byte(vm.PUSH2), 0x01}, 3); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests the truncation boundary of every push width at the end of the code
+func TestInstructionIteratorPushBoundary(t *testing.T) { log.DebugLog()
+	for width := 1; width <= 32; width++ {
+		op := vm.PUSH1 + vm.OpCode(width-1)
+		for have := 0; have <= width; have++ {
+			// Prefix the push with an ADD so it does not start at pc zero
+			script := append([]byte{byte(vm.ADD), byte(op)}, bytes.Repeat([]byte{0xff}, have)...)
+
+			it := NewInstructionIterator(script)
+			it.Next()
+			ok := it.Next()
+			if complete := have == width; ok != complete || (it.Error() == nil) != complete {
+				t.Errorf("%v with %d bytes: expected complete %v, but got next %v, error %v.", op, have, complete, ok, it.Error())
+				continue
+			}
+			if len(it.Arg()) != have {
+				t.Errorf("%v with %d bytes: expected %d argument bytes, but got %d.", op, have, have, len(it.Arg()))
+			}
+			if it.Next() {
+				t.Errorf("%v with %d bytes: expected the end, but got %v.", op, have, it.Op())
+			}
+		}
+	}
+}
+
+// Tests the details reported for truncated pushes
+func TestErrTruncatedPush(t *testing.T) { log.DebugLog()
+	err := Validate([]byte{byte(vm.ADD), byte(vm.PUSH4), 0x01, 0x02})
+
+	var perr *ErrTruncatedPush
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected ErrTruncatedPush, but got %v instead.", err)
+	}
+	if want := (ErrTruncatedPush{PC: 1, Op: vm.PUSH4, Need: 4, Have: 2}); *perr != want {
+		t.Errorf("Expected %+v, but got %+v instead.", want, *perr)
+	}
+	if want := "incomplete PUSH4 at pc 1: need 4 bytes, have 2"; err.Error() != want {
+		t.Errorf("Expected %q, but got %q instead.", want, err.Error())
+	}
+}
+
+// Tests the structural validation of code
+func TestValidate(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		code string
+		fail bool
+	}{
+		{"", false},
+		{"61000000", false},
+		{"5f01", false},
+		{"6001", false},
+		{"60", true},
+		{"600161", true},
+		{"7f0001", true},
+	}
+	for _, test := range tests {
+		script, _ := hex.DecodeString(test.code)
+		if err := Validate(script); (err != nil) != test.fail {
+			t.Errorf("code %s: expected failure %v, but got error %v.", test.code, test.fail, err)
+		}
+	}
+}
+
+// Tests finding the instruction preceding a given pc
+func TestPrevInstruction(t *testing.T) { log.DebugLog()
+	// PUSH2 0x5b5b JUMPDEST ADD
+	script, _ := hex.DecodeString("615b5b5b01")
+
+	tests := []struct {
+		pc   uint64
+		want uint64
+		fail bool
+	}{
+		{0, 0, true},
+		{1, 0, true},
+		{2, 0, true},
+		{3, 0, false},
+		{4, 3, false},
+		{5, 4, false},
+		{6, 0, true},
+	}
+	for _, test := range tests {
+		instr, err := PrevInstruction(script, test.pc)
+		if (err != nil) != test.fail {
+			t.Errorf("pc %v: expected failure %v, but got error %v.", test.pc, test.fail, err)
+			continue
+		}
+		if err == nil && instr.PC != test.want {
+			t.Errorf("pc %v: expected previous pc %v, but got %v instead.", test.pc, test.want, instr.PC)
+		}
+	}
+	if instr, _ := PrevInstruction(script, 3); instr.Op != vm.PUSH2 || !bytes.Equal(instr.Arg, []byte{0x5b, 0x5b}) {
+		t.Errorf("Expected PUSH2 0x5b5b, but got %+v instead.", instr)
+	}
+}
+
+// Tests that context aware disassembly honours cancellation
+func TestDisassembleContext(t *testing.T) { log.DebugLog()
+	script := bytes.Repeat([]byte{byte(vm.JUMPDEST)}, 2*contextCheckInterval)
+
+	instrs, err := DisassembleContext(context.Background(), script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if len(instrs) != len(script) {
+		t.Errorf("Expected %d instructions, but got %d instead.", len(script), len(instrs))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DisassembleContext(ctx, script); err != context.Canceled {
+		t.Errorf("Expected %v, but got %v instead.", context.Canceled, err)
+	}
+}
+
+// Tests disassembling from a non-zero offset
+func TestDisassembleFrom(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6080600101")
+
+	tests := []struct {
+		start uint64
+		want  []string
+	}{
+		{0, []string{"000000: PUSH1 0x80\n", "000002: PUSH1 0x01\n", "000004: ADD\n"}},
+		{2, []string{"000002: PUSH1 0x01\n", "000004: ADD\n"}},
+		{5, []string{}},
+		{100, []string{}},
+	}
+	for _, test := range tests {
+		instrs, err := DisassembleFrom(script, test.start)
+		if err != nil {
+			t.Errorf("start %d: expected no error, but encountered %v instead.", test.start, err)
+			continue
+		}
+		if !reflect.DeepEqual(instrs, test.want) {
+			t.Errorf("start %d: expected %q, but got %q instead.", test.start, test.want, instrs)
+		}
+	}
+}
+
+// Tests walking instructions and stopping early
+func TestWalk(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6080600101f3")
+
+	var pcs []uint64
+	errStop := errors.New("stop")
+	err := Walk(script, func(pc uint64, op vm.OpCode, arg []byte) error {
+		pcs = append(pcs, pc)
+		if op == vm.ADD {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Expected %v, but got %v instead.", errStop, err)
+	}
+	if want := []uint64{0, 2, 4}; !reflect.DeepEqual(pcs, want) {
+		t.Errorf("Expected %v, but got %v instead.", want, pcs)
+	}
+
+	script, _ = hex.DecodeString("600161")
+	if err := Walk(script, func(uint64, vm.OpCode, []byte) error { return nil }); err == nil {
+		t.Errorf("Expected an error, but got none.")
+	}
+}
+
+// Tests disassembling hex strings in the various accepted forms
+func TestDisassembleHex(t *testing.T) { log.DebugLog()
+	want := []string{"000000: PUSH1 0x80\n", "000002: STOP\n"}
+	for _, code := range []string{"608000", "0x608000", "0X608000", "  0x608000\n"} {
+		instrs, err := DisassembleHex(code)
+		if err != nil {
+			t.Errorf("code %q: expected no error, but encountered %v instead.", code, err)
+			continue
+		}
+		if !reflect.DeepEqual(instrs, want) {
+			t.Errorf("code %q: expected %q, but got %q instead.", code, want, instrs)
+		}
+	}
+	_, err := DisassembleHex(" 0x60g0")
+	if err == nil || err.Error() != `invalid bytecode hex at position 5: encoding/hex: invalid byte: U+0067 'g'` {
+		t.Errorf("Expected positional error, but got %v instead.", err)
+	}
+	var invalid hex.InvalidByteError
+	if !errors.As(err, &invalid) || invalid != 'g' {
+		t.Errorf("Expected wrapped %T, but got %v instead.", invalid, err)
+	}
+	if _, err := DisassembleHex("0x608"); !errors.Is(err, hex.ErrLength) {
+		t.Errorf("Expected %v, but got %v instead.", hex.ErrLength, err)
+	}
+}
+
+// Tests writing the disassembly as CSV
+func TestDisassembleCSV(t *testing.T) { log.DebugLog()
+	var buf bytes.Buffer
+	if err := DisassembleCSV(&buf, []byte{byte(vm.PUSH2), 0xab, 0xcd, byte(vm.ADD), 0x0c}); err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := "pc,index,opcode_hex,mnemonic,arg_hex\n" +
+		"0,0,61,PUSH2,abcd\n" +
+		"3,1,01,ADD,\n" +
+		"4,2,0c,opcode 0x0c,\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, but got %q instead.", want, buf.String())
+	}
+	buf.Reset()
+	if err := DisassembleCSV(&buf, []byte{byte(vm.ADD), byte(vm.PUSH1)}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+	if want := "pc,index,opcode_hex,mnemonic,arg_hex\n0,0,01,ADD,\n"; buf.String() != want {
+		t.Errorf("Expected %q, but got %q instead.", want, buf.String())
+	}
+}
+
+// Tests stopping the output at a byte budget
+func TestFprintDisassembledLimit(t *testing.T) { log.DebugLog()
+	// Every line is 19 bytes long
+	script, _ := hex.DecodeString("600160026003")
+
+	var buf bytes.Buffer
+	written, err := FprintDisassembledLimit(&buf, script, 50)
+	if err != ErrOutputLimit {
+		t.Errorf("Expected ErrOutputLimit, but got %v instead.", err)
+	}
+	if want := "000000: PUSH1 0x01\n000002: PUSH1 0x02\n"; buf.String() != want || written != len(want) {
+		t.Errorf("Expected %q (%d bytes), but got %q (%d bytes).", want, len(want), buf.String(), written)
+	}
+	buf.Reset()
+	if written, err := FprintDisassembledLimit(&buf, script, 57); err != nil || written != 57 {
+		t.Errorf("Expected all 57 bytes, but got %d (%v).", written, err)
+	}
+}
+
+// Tests printing disassembled code given as prefixed hex
+func TestFprintDisassembledHex(t *testing.T) { log.DebugLog()
+	var buf bytes.Buffer
+	if err := FprintDisassembled(&buf, "0x608000"); err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := "000000: PUSH1 0x80\n000002: STOP\n"; buf.String() != want {
+		t.Errorf("Expected %q, but got %q instead.", want, buf.String())
+	}
+	if err := FprintDisassembled(&buf, "0x608"); !errors.Is(err, hex.ErrLength) {
+		t.Errorf("Expected %v, but got %v instead.", hex.ErrLength, err)
+	}
+}
+
+// Tests that unterminated lines join cleanly and assemble back
+func TestDisassembleLines(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6080604052")
+
+	lines, err := DisassembleLines(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := "000000: PUSH1 0x80\n000002: PUSH1 0x40\n000004: MSTORE"
+	if joined := strings.Join(lines, "\n"); joined != want {
+		t.Errorf("Expected %q, but got %q instead.", want, joined)
+	}
+	code, err := Assemble(strings.Join(lines, "\n"))
+	if err != nil || !bytes.Equal(code, script) {
+		t.Errorf("Expected %x, but got %x (%v) instead.", script, code, err)
+	}
+}
+
+// Tests disassembling with a custom formatter
+func TestDisassembleFormat(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("608001")
+
+	instrs, err := DisassembleFormat(script, func(instr Instruction) string {
+		return fmt.Sprintf("%d\t%v\t%x", instr.PC, instr.Op, instr.Arg)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []string{"0\tPUSH1\t80", "2\tADD\t"}; !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+	if got := DefaultFormat(Instruction{PC: 2, Op: vm.ADD}); got != "000002: ADD\n" {
+		t.Errorf("Expected default format, but got %q instead.", got)
+	}
+}
+
+// Tests that strict disassembly rejects empty code
+func TestDisassembleStrict(t *testing.T) { log.DebugLog()
+	for _, script := range [][]byte{nil, {}} {
+		if _, err := DisassembleStrict(script); err != ErrEmptyCode {
+			t.Errorf("Expected ErrEmptyCode, but got %v instead.", err)
+		}
+		if instrs, err := Disassemble(script); err != nil || len(instrs) != 0 {
+			t.Errorf("Expected no instructions and no error, but got %q (%v).", instrs, err)
+		}
+	}
+	if instrs, err := DisassembleStrict([]byte{byte(vm.STOP)}); err != nil || len(instrs) != 1 {
+		t.Errorf("Expected 1 instruction, but got %q (%v).", instrs, err)
+	}
+}
+
+// Tests tagging lines with the bytes they were decoded from
+func TestDisassembleWithRanges(t *testing.T) { log.DebugLog()
+	lines, err := DisassembleWithRanges([]byte{byte(vm.PUSH2), 0xab, 0xcd, byte(vm.PUSH0), byte(vm.ADD)})
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []RangedLine{
+		{"000000: PUSH2 0xabcd", 0, 3},
+		{"000003: PUSH0", 3, 4},
+		{"000004: ADD", 4, 5},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Expected %+v, but got %+v instead.", want, lines)
+	}
+	if _, err := DisassembleWithRanges([]byte{byte(vm.PUSH2)}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests prefixing lines with the instruction index
+func TestDisassembleIndexed(t *testing.T) { log.DebugLog()
+	instrs, err := DisassembleIndexed([]byte{byte(vm.PUSH1), 0x01, byte(vm.DUP1), byte(vm.ADD)})
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{"[0000] 000000: PUSH1 0x01\n", "[0001] 000002: DUP1\n", "[0002] 000003: ADD\n"}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+}
+
+// Tests printing lowercase mnemonics
+func TestDisassembleLower(t *testing.T) { log.DebugLog()
+	instrs, err := DisassembleLower([]byte{byte(vm.PUSH2), 0xab, 0xcd, byte(vm.SSTORE), 0x0c})
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{"000000: push2 0xabcd\n", "000003: sstore\n", "000004: opcode 0x0c\n"}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+}
+
+// Tests comparing instructions semantically
+func TestInstructionEqual(t *testing.T) { log.DebugLog()
+	a := Instruction{PC: 0, Op: vm.PUSH1, Arg: []byte{0x01}}
+	if !a.Equal(Instruction{PC: 9, Op: vm.PUSH1, Arg: []byte{0x01}}) {
+		t.Errorf("Expected instructions at different PCs to be equal.")
+	}
+	if a.Equal(Instruction{Op: vm.PUSH1, Arg: []byte{0x02}}) || !a.EqualIgnoringArg(Instruction{Op: vm.PUSH1, Arg: []byte{0x02}}) {
+		t.Errorf("Expected argument to matter only for Equal.")
+	}
+	if a.EqualIgnoringArg(Instruction{Op: vm.PUSH2, Arg: []byte{0x00, 0x01}}) {
+		t.Errorf("Expected different opcodes to differ.")
+	}
+	if !(Instruction{Op: vm.PUSH0, Arg: []byte{}}).Equal(Instruction{Op: vm.PUSH0}) {
+		t.Errorf("Expected empty and missing arguments to be equal.")
+	}
+}
+
+// Tests the string form of instructions
+func TestInstructionString(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		instr Instruction
+		want  string
+	}{
+		{Instruction{PC: 0, Op: vm.PUSH2, Arg: []byte{0x00, 0x80}}, "000000: PUSH2 0x0080"},
+		{Instruction{PC: 12, Op: vm.ADD}, "000012: ADD"},
+		{Instruction{PC: 13, Op: vm.PUSH0, Arg: []byte{}}, "000013: PUSH0"},
+		{Instruction{PC: 14, Op: vm.OpCode(0x0c)}, "000014: opcode 0x0c"},
+	}
+	for _, test := range tests {
+		if got := test.instr.String(); got != test.want {
+			t.Errorf("Expected %q, but got %q instead.", test.want, got)
+		}
+	}
+}
+
+// Benchmarks disassembling a contract of the maximum deployable size
+func BenchmarkDisassemble(b *testing.B) { log.DebugLog()
+	code := bytes.Repeat([]byte{byte(vm.PUSH1), 0x80, byte(vm.ADD)}, 24576/3)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Disassemble(code); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Assemble converts a listing in the format produced by Disassemble back into
+// EVM bytecode. Every line holds a mnemonic, optionally prefixed by its PC and
+// followed by a 0x prefixed hex argument for push instructions. Undefined
+// opcodes are given as "opcode 0xNN". Everything after a ';' is a comment,
+// blank lines are skipped.
+func Assemble(asm string) ([]byte, error) { log.DebugLog()
+	var code []byte
+	for i, line := range strings.Split(asm, "\n") {
+		if idx := strings.IndexByte(line, ';'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		// Drop the PC prefix emitted by the disassembler
+		if strings.HasSuffix(fields[0], ":") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: malformed instruction %q", i+1, line)
+		}
+		// Undefined opcodes are rendered as "opcode 0xNN"
+		if fields[0] == "opcode" {
+			if len(fields) != 2 || len(fields[1]) != 4 || !strings.HasPrefix(fields[1], "0x") {
+				return nil, fmt.Errorf("line %d: malformed undefined opcode %q", i+1, line)
+			}
+			b, err := hex.DecodeString(fields[1][2:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid undefined opcode %q: %v", i+1, fields[1], err)
+			}
+			code = append(code, b...)
+			continue
+		}
+		op := vm.StringToOp(fields[0])
+		if op.String() != fields[0] {
+			return nil, fmt.Errorf("line %d: unknown opcode %q", i+1, fields[0])
+		}
+		var arg []byte
+		if len(fields) == 2 {
+			if !strings.HasPrefix(fields[1], "0x") {
+				return nil, fmt.Errorf("line %d: argument %q is not 0x prefixed", i+1, fields[1])
+			}
+			var err error
+			if arg, err = hex.DecodeString(fields[1][2:]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid argument %q: %v", i+1, fields[1], err)
+			}
+		}
+		instr, err := EncodeInstruction(op, arg)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		code = append(code, instr...)
+	}
+	return code, nil
+}
+
+// EncodeInstruction returns the bytecode of a single instruction. PUSHn
+// expects exactly n argument bytes, all other opcodes none.
+func EncodeInstruction(op vm.OpCode, arg []byte) ([]byte, error) { log.DebugLog()
+	want := 0
+	if op.IsPush() {
+		want = int(op-vm.PUSH1) + 1
+	}
+	if len(arg) != want {
+		return nil, fmt.Errorf("%v expects %d argument bytes, got %d", op, want, len(arg))
+	}
+	return append([]byte{byte(op)}, arg...), nil
+}
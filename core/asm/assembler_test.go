@@ -0,0 +1,134 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that disassembled code assembles back into the original bytecode
+func TestAssembleRoundTrip(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6080604052348015600f57600080fd5b5f7f00000000000000000000000000000000000000000000000000000000000000ff00")
+
+	instrs, err := Disassemble(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	code, err := Assemble(strings.Join(instrs, ""))
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if !bytes.Equal(code, script) {
+		t.Errorf("Expected %x, but got %x instead.", script, code)
+	}
+	// Undefined opcodes, as found in metadata trailers and data tables
+	script, _ = hex.DecodeString("60800c0d21a1b2c30009")
+
+	instrs, err = Disassemble(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if code, err = Assemble(strings.Join(instrs, "")); err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if !bytes.Equal(code, script) {
+		t.Errorf("Expected %x, but got %x instead.", script, code)
+	}
+	for _, asm := range []string{"opcode", "opcode 0x0", "opcode 0x0c0d", "opcode 0xzz", "opcode 0c"} {
+		if _, err := Assemble(asm); err == nil {
+			t.Errorf("%q: expected error, but got none.", asm)
+		}
+	}
+}
+
+// Tests that comments and blank lines are ignored
+func TestAssembleComments(t *testing.T) { log.DebugLog()
+	asm := `; function prologue
+PUSH1 0x60 ; free memory pointer
+  	
+000002: PUSH1 0x40;no space before the comment
+MSTORE
+; trailing comment`
+
+	code, err := Assemble(asm)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if want := []byte{0x60, 0x60, 0x60, 0x40, 0x52}; !bytes.Equal(code, want) {
+		t.Errorf("Expected %x, but got %x instead.", want, code)
+	}
+}
+
+// Tests encoding single instructions
+func TestEncodeInstruction(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		op   vm.OpCode
+		arg  []byte
+		want []byte
+		fail bool
+	}{
+		{vm.PUSH2, []byte{0x01, 0x02}, []byte{0x61, 0x01, 0x02}, false},
+		{vm.PUSH0, nil, []byte{0x5f}, false},
+		{vm.ADD, nil, []byte{0x01}, false},
+		{vm.PUSH2, []byte{0x01}, nil, true},
+		{vm.PUSH1, nil, nil, true},
+		{vm.ADD, []byte{0x01}, nil, true},
+	}
+	for _, test := range tests {
+		code, err := EncodeInstruction(test.op, test.arg)
+		if (err != nil) != test.fail {
+			t.Errorf("%v %x: expected failure %v, but got error %v.", test.op, test.arg, test.fail, err)
+		}
+		if !bytes.Equal(code, test.want) {
+			t.Errorf("%v %x: expected %x, but got %x instead.", test.op, test.arg, test.want, code)
+		}
+	}
+	// Decoding the encoded instruction yields it back
+	arg := bytes.Repeat([]byte{0xaa}, 32)
+	code, _ := EncodeInstruction(vm.PUSH32, arg)
+	it := NewInstructionIterator(code)
+	if !it.Next() || it.Op() != vm.PUSH32 || !bytes.Equal(it.Arg(), arg) {
+		t.Errorf("Expected PUSH32 0x%x, but got %v 0x%x.", arg, it.Op(), it.Arg())
+	}
+}
+
+// Tests that malformed listings are rejected with the offending line
+func TestAssembleErrors(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		asm string
+		err string
+	}{
+		{"PUSH1 0x60\nFOO", "line 2: unknown opcode"},
+		{"PUSH2 0x60", "line 1: PUSH2 expects 2 argument bytes, got 1"},
+		{"ADD 0x01", "line 1: ADD expects 0 argument bytes, got 1"},
+		{"PUSH1 60", "line 1: argument \"60\" is not 0x prefixed"},
+		{"PUSH1 0xzz", "line 1: invalid argument"},
+		{"PUSH1 0x60 0x60", "line 1: malformed instruction"},
+	}
+	for _, test := range tests {
+		_, err := Assemble(test.asm)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("asm %q: expected error %q, but got %v instead.", test.asm, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// PanicError is reported by DisassembleBatch for an input whose disassembly
+// panicked, so a single bad input does not bring down the whole batch.
+type PanicError struct {
+	Value interface{} // Value passed to panic
+}
+
+func (e *PanicError) Error() string { log.DebugLog()
+	return fmt.Sprintf("disassembly panicked: %v", e.Value)
+}
+
+// disassembleRecover runs fn, converting a panic into a PanicError.
+func disassembleRecover(ctx context.Context, script []byte, fn func(context.Context, []byte) ([]string, error)) (instrs []string, err error) { log.DebugLog()
+	defer func() {
+		if r := recover(); r != nil {
+			instrs, err = nil, &PanicError{Value: r}
+		}
+	}()
+	return fn(ctx, script)
+}
+
+// DisassembleBatch disassembles multiple codes concurrently using the given
+// number of workers. Results and errors are returned in input order. Once the
+// context is cancelled, inputs that were not yet processed fail with the
+// context error. A panic while disassembling an input fails it with a
+// PanicError.
+func DisassembleBatch(ctx context.Context, codes [][]byte, workers int) ([][]string, []error) { log.DebugLog()
+	return disassembleBatch(ctx, codes, workers, DisassembleContext)
+}
+
+// disassembleBatch implements DisassembleBatch, disassembling each input with
+// fn.
+func disassembleBatch(ctx context.Context, codes [][]byte, workers int, fn func(context.Context, []byte) ([]string, error)) ([][]string, []error) { log.DebugLog()
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		results = make([][]string, len(codes))
+		errs    = make([]error, len(codes))
+		tasks   = make(chan int)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range tasks {
+				results[index], errs[index] = disassembleRecover(ctx, codes[index], fn)
+			}
+		}()
+	}
+	// Feed the workers until done or cancelled, failing the remaining inputs
+	for index := range codes {
+		select {
+		case tasks <- index:
+			continue
+		case <-ctx.Done():
+		}
+		for ; index < len(codes); index++ {
+			errs[index] = ctx.Err()
+		}
+		break
+	}
+	close(tasks)
+	wg.Wait()
+
+	return results, errs
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that batch results are returned in input order
+func TestDisassembleBatch(t *testing.T) { log.DebugLog()
+	codes := make([][]byte, 50)
+	for i := range codes {
+		codes[i] = []byte{0x60, byte(i)}
+	}
+	codes[7] = []byte{0x61, 0x00}
+
+	results, errs := DisassembleBatch(context.Background(), codes, 4)
+	for i := range codes {
+		if i == 7 {
+			if errs[i] == nil {
+				t.Errorf("input %d: expected an error, but got none.", i)
+			}
+			continue
+		}
+		want, _ := Disassemble(codes[i])
+		if errs[i] != nil || !reflect.DeepEqual(results[i], want) {
+			t.Errorf("input %d: expected %q, but got %q (%v).", i, want, results[i], errs[i])
+		}
+	}
+}
+
+// Tests that a cancelled context fails all inputs
+func TestDisassembleBatchCancelled(t *testing.T) { log.DebugLog()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, errs := DisassembleBatch(ctx, [][]byte{{0x00}, {0x01}, {0x02}}, 2)
+	for i, err := range errs {
+		if err != context.Canceled {
+			t.Errorf("input %d: expected %v, but got %v instead.", i, context.Canceled, err)
+		}
+	}
+}
+
+// Tests that a panicking input does not affect the rest of the batch
+func TestDisassembleBatchPanic(t *testing.T) { log.DebugLog()
+	fn := func(ctx context.Context, script []byte) ([]string, error) {
+		if len(script) == 0 {
+			panic("boom")
+		}
+		return DisassembleContext(ctx, script)
+	}
+	results, errs := disassembleBatch(context.Background(), [][]byte{{0x00}, {}, {0x01}}, 2, fn)
+
+	if perr, ok := errs[1].(*PanicError); !ok || perr.Value != "boom" {
+		t.Errorf("Expected panic error, but got %v instead.", errs[1])
+	}
+	for _, i := range []int{0, 2} {
+		if errs[i] != nil || len(results[i]) != 1 {
+			t.Errorf("input %d: expected 1 instruction, but got %q (%v).", i, results[i], errs[i])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Builder constructs bytecode instruction by instruction. Its methods can be
+// chained; the first error encountered is retained and returned by Bytes,
+// later calls are then ignored.
+type Builder struct {
+	code   []byte
+	labels map[string]uint64 // Offsets of the defined labels
+	refs   []labelRef        // Pushes to patch with label offsets
+	err    error
+}
+
+// labelRef is a PUSH2 whose argument is the offset of a label.
+type labelRef struct {
+	name string
+	pc   uint64 // Offset of the PUSH2 instruction
+}
+
+// NewBuilder creates an empty bytecode builder.
+func NewBuilder() *Builder { log.DebugLog()
+	return &Builder{labels: make(map[string]uint64)}
+}
+
+// Op appends an instruction without argument. Pushes with immediate data must
+// be added through Push instead.
+func (b *Builder) Op(op vm.OpCode) *Builder { log.DebugLog()
+	return b.emit(op, nil)
+}
+
+// Push appends a push of the given data, selecting the PUSHn matching its
+// length, which must be between 1 and 32 bytes.
+func (b *Builder) Push(data []byte) *Builder { log.DebugLog()
+	if len(data) < 1 || len(data) > 32 {
+		if b.err == nil {
+			b.err = fmt.Errorf("push data must be 1 to 32 bytes, got %d", len(data))
+		}
+		return b
+	}
+	return b.emit(vm.PUSH1+vm.OpCode(len(data)-1), data)
+}
+
+// JumpDest appends a JUMPDEST and returns its PC.
+func (b *Builder) JumpDest() (*Builder, uint64) { log.DebugLog()
+	pc := uint64(len(b.code))
+	return b.Op(vm.JUMPDEST), pc
+}
+
+// Label defines the name for the current offset. It does not emit any code,
+// so jump targets should be followed by a JumpDest.
+func (b *Builder) Label(name string) *Builder { log.DebugLog()
+	if b.err != nil {
+		return b
+	}
+	if _, ok := b.labels[name]; ok {
+		b.err = fmt.Errorf("duplicate label %q", name)
+		return b
+	}
+	b.labels[name] = uint64(len(b.code))
+	return b
+}
+
+// PushLabel appends a PUSH2 of the offset of the named label, which may be
+// defined later on. The offset is filled in by Bytes.
+func (b *Builder) PushLabel(name string) *Builder { log.DebugLog()
+	if b.err == nil {
+		b.refs = append(b.refs, labelRef{name: name, pc: uint64(len(b.code))})
+	}
+	return b.emit(vm.PUSH2, []byte{0, 0})
+}
+
+// Bytes returns the constructed bytecode with all label references resolved,
+// or the first error encountered.
+func (b *Builder) Bytes() ([]byte, error) { log.DebugLog()
+	if b.err != nil {
+		return nil, b.err
+	}
+	code := append([]byte(nil), b.code...)
+	for _, ref := range b.refs {
+		target, ok := b.labels[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("pc %d: undefined label %q", ref.pc, ref.name)
+		}
+		if target > 0xffff {
+			return nil, fmt.Errorf("pc %d: label %q at %d does not fit PUSH2", ref.pc, ref.name, target)
+		}
+		code[ref.pc+1], code[ref.pc+2] = byte(target>>8), byte(target)
+	}
+	return code, nil
+}
+
+// emit appends an encoded instruction unless an error occurred before.
+func (b *Builder) emit(op vm.OpCode, arg []byte) *Builder { log.DebugLog()
+	if b.err != nil {
+		return b
+	}
+	instr, err := EncodeInstruction(op, arg)
+	if err != nil {
+		b.err = fmt.Errorf("pc %d: %v", len(b.code), err)
+		return b
+	}
+	b.code = append(b.code, instr...)
+	return b
+}
@@ -0,0 +1,79 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests constructing bytecode fluently
+func TestBuilder(t *testing.T) { log.DebugLog()
+	b, dest := NewBuilder().Push([]byte{0x01}).Push([]byte{0x02, 0x03}).Op(vm.ADD).JumpDest()
+	if dest != 6 {
+		t.Errorf("Expected JUMPDEST at 6, but got %d instead.", dest)
+	}
+	code, err := b.Op(vm.PUSH0).Push(bytes.Repeat([]byte{0xff}, 32)).Op(vm.STOP).Bytes()
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := append([]byte{0x60, 0x01, 0x61, 0x02, 0x03, 0x01, 0x5b, 0x5f, 0x7f}, bytes.Repeat([]byte{0xff}, 32)...)
+	want = append(want, 0x00)
+	if !bytes.Equal(code, want) {
+		t.Errorf("Expected %x, but got %x instead.", want, code)
+	}
+}
+
+// Tests resolving forward and backward label references
+func TestBuilderLabels(t *testing.T) { log.DebugLog()
+	b, _ := NewBuilder().PushLabel("end").Op(vm.JUMP).Label("loop").JumpDest()
+	code, err := b.PushLabel("loop").Op(vm.JUMP).Label("end").Op(vm.JUMPDEST).Bytes()
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	// PUSH2 0x0009 JUMP JUMPDEST PUSH2 0x0004 JUMP JUMPDEST
+	want := []byte{0x61, 0x00, 0x09, 0x56, 0x5b, 0x61, 0x00, 0x04, 0x56, 0x5b}
+	if !bytes.Equal(code, want) {
+		t.Errorf("Expected %x, but got %x instead.", want, code)
+	}
+	edges, _ := JumpEdges(code)
+	if len(edges) != 2 || edges[0].ToPC != 9 || edges[1].ToPC != 4 {
+		t.Errorf("Expected jumps to 9 and 4, but got %+v.", edges)
+	}
+	if _, err := NewBuilder().PushLabel("missing").Bytes(); err == nil {
+		t.Errorf("Expected undefined label error, but got none.")
+	}
+	if _, err := NewBuilder().Label("a").Op(vm.STOP).Label("a").Bytes(); err == nil {
+		t.Errorf("Expected duplicate label error, but got none.")
+	}
+}
+
+// Tests that invalid pushes fail the construction
+func TestBuilderErrors(t *testing.T) { log.DebugLog()
+	for _, b := range []*Builder{
+		NewBuilder().Push(nil),
+		NewBuilder().Push(make([]byte, 33)),
+		NewBuilder().Op(vm.ADD).Op(vm.PUSH1).Op(vm.STOP),
+	} {
+		if code, err := b.Bytes(); err == nil {
+			t.Errorf("Expected error, but got code %x.", code)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Cache memoizes disassembled code keyed by its keccak256 hash, evicting the
+// least recently used entries once full. It is safe for concurrent use.
+type Cache struct {
+	entries *lru.Cache // Disassembled instructions by code hash
+}
+
+// NewCache creates a disassembly cache holding at most maxEntries programs. A
+// non-positive limit is treated as 1.
+func NewCache(maxEntries int) *Cache { log.DebugLog()
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	entries, _ := lru.New(maxEntries)
+	return &Cache{entries: entries}
+}
+
+// Disassemble returns the same result as the package level Disassemble, but
+// serves repeated code from the cache. Failed disassemblies are not cached.
+func (c *Cache) Disassemble(script []byte) ([]string, error) { log.DebugLog()
+	hash := crypto.Keccak256Hash(script)
+	if cached, ok := c.entries.Get(hash); ok {
+		return append([]string(nil), cached.([]string)...), nil
+	}
+	instrs, err := Disassemble(script)
+	if err != nil {
+		return nil, err
+	}
+	c.entries.Add(hash, instrs)
+	return append([]string(nil), instrs...), nil
+}
+
+// Len returns the number of cached programs.
+func (c *Cache) Len() int { log.DebugLog()
+	return c.entries.Len()
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that cached disassemblies match and old entries are evicted
+func TestCache(t *testing.T) { log.DebugLog()
+	cache := NewCache(2)
+
+	codes := [][]byte{{byte(vm.PUSH1), 0x01}, {byte(vm.ADD)}, {byte(vm.STOP)}}
+	for _, code := range codes {
+		want, _ := Disassemble(code)
+		for i := 0; i < 2; i++ {
+			got, err := cache.Disassemble(code)
+			if err != nil {
+				t.Fatalf("Expected no error, but encountered %v instead.", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %q, but got %q instead.", want, got)
+			}
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached entries, but got %d instead.", cache.Len())
+	}
+	// Mutating a result must not corrupt the cache
+	got, _ := cache.Disassemble(codes[2])
+	got[0] = "corrupt"
+	if again, _ := cache.Disassemble(codes[2]); again[0] == "corrupt" {
+		t.Errorf("Expected cached result to be isolated from callers.")
+	}
+	if _, err := cache.Disassemble([]byte{byte(vm.PUSH2)}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected failures not to be cached, but got %d entries.", cache.Len())
+	}
+}
+
+// Tests using the cache from multiple goroutines
+func TestCacheConcurrent(t *testing.T) { log.DebugLog()
+	cache := NewCache(4)
+	code := []byte{byte(vm.PUSH1), 0x01, byte(vm.ADD)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if instrs, err := cache.Disassemble(code); err != nil || len(instrs) != 2 {
+				t.Errorf("Expected 2 instructions, but got %d (%v).", len(instrs), err)
+			}
+		}()
+	}
+	wg.Wait()
+}
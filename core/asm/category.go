@@ -0,0 +1,158 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// OpCategory is the functional group an opcode belongs to.
+type OpCategory int
+
+const (
+	CategoryUnknown       OpCategory = iota // Undefined opcodes
+	CategoryArithmetic                      // Arithmetic and hashing
+	CategoryComparison                      // Comparisons and ISZERO
+	CategoryBitwise                         // Bitwise logic and shifts
+	CategoryEnvironmental                   // Execution environment and GAS
+	CategoryBlock                           // Block information
+	CategoryStack                           // POP
+	CategoryMemory                          // Memory access
+	CategoryStorage                         // Storage access
+	CategoryFlow                            // Jumps, halting and PC
+	CategorySystem                          // Calls, creation, returning and reverting
+	CategoryPush                            // PUSH0 to PUSH32
+	CategoryDup                             // DUP1 to DUP16
+	CategorySwap                            // SWAP1 to SWAP16
+	CategoryLog                             // LOG0 to LOG4
+)
+
+var categoryNames = [...]string{
+	CategoryUnknown:       "unknown",
+	CategoryArithmetic:    "arithmetic",
+	CategoryComparison:    "comparison",
+	CategoryBitwise:       "bitwise",
+	CategoryEnvironmental: "environmental",
+	CategoryBlock:         "block",
+	CategoryStack:         "stack",
+	CategoryMemory:        "memory",
+	CategoryStorage:       "storage",
+	CategoryFlow:          "flow",
+	CategorySystem:        "system",
+	CategoryPush:          "push",
+	CategoryDup:           "dup",
+	CategorySwap:          "swap",
+	CategoryLog:           "log",
+}
+
+func (c OpCategory) String() string { log.DebugLog()
+	if c < 0 || int(c) >= len(categoryNames) {
+		return "unknown"
+	}
+	return categoryNames[c]
+}
+
+// Category returns the functional group of the opcode.
+func Category(op vm.OpCode) OpCategory { log.DebugLog()
+	switch {
+	case op == vm.PUSH0 || op.IsPush():
+		return CategoryPush
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return CategoryDup
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return CategorySwap
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return CategoryLog
+	case op >= vm.ADD && op <= vm.SIGNEXTEND, op == vm.SHA3:
+		return CategoryArithmetic
+	case op >= vm.LT && op <= vm.ISZERO:
+		return CategoryComparison
+	case op >= vm.AND && op <= vm.SAR:
+		return CategoryBitwise
+	case op >= vm.ADDRESS && op <= vm.RETURNDATACOPY, op == vm.GAS:
+		return CategoryEnvironmental
+	case op >= vm.BLOCKHASH && op <= vm.GASLIMIT:
+		return CategoryBlock
+	}
+	switch op {
+	case vm.POP:
+		return CategoryStack
+	case vm.MLOAD, vm.MSTORE, vm.MSTORE8, vm.MSIZE:
+		return CategoryMemory
+	case vm.SLOAD, vm.SSTORE:
+		return CategoryStorage
+	case vm.STOP, vm.JUMP, vm.JUMPI, vm.PC, vm.JUMPDEST:
+		return CategoryFlow
+	case vm.CREATE, vm.CALL, vm.CALLCODE, vm.RETURN, vm.DELEGATECALL, vm.CREATE2,
+		vm.STATICCALL, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT:
+		return CategorySystem
+	}
+	return CategoryUnknown
+}
+
+// DupIndex returns the 1-based stack position duplicated by a DUPn opcode, and
+// whether the opcode is a DUPn at all.
+func DupIndex(op vm.OpCode) (int, bool) { log.DebugLog()
+	if op < vm.DUP1 || op > vm.DUP16 {
+		return 0, false
+	}
+	return int(op-vm.DUP1) + 1, true
+}
+
+// SwapIndex returns the 1-based stack position exchanged with the top by a
+// SWAPn opcode, and whether the opcode is a SWAPn at all.
+func SwapIndex(op vm.OpCode) (int, bool) { log.DebugLog()
+	if op < vm.SWAP1 || op > vm.SWAP16 {
+		return 0, false
+	}
+	return int(op-vm.SWAP1) + 1, true
+}
+
+// PushWidth returns the number of immediate bytes following a push opcode, and
+// whether the opcode is a push at all. PUSH0 has a width of zero.
+func PushWidth(op vm.OpCode) (int, bool) { log.DebugLog()
+	switch {
+	case op == vm.PUSH0:
+		return 0, true
+	case op.IsPush():
+		return int(op-vm.PUSH1) + 1, true
+	}
+	return 0, false
+}
+
+// CategorizedInstruction is a disassembled instruction with the functional
+// group of its opcode.
+type CategorizedInstruction struct {
+	Instruction
+	Category OpCategory
+}
+
+// Return all disassembled EVM instructions in structured format, paired with
+// the category of their opcode.
+func DisassembleCategorized(script []byte) ([]CategorizedInstruction, error) { log.DebugLog()
+	instrs := make([]CategorizedInstruction, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		instrs = append(instrs, CategorizedInstruction{it.Instruction(), Category(it.Op())})
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
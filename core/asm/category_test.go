@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests the category of every defined opcode
+func TestCategory(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		ops      []vm.OpCode
+		category OpCategory
+	}{
+		{[]vm.OpCode{vm.ADD, vm.MULMOD, vm.SIGNEXTEND, vm.SHA3}, CategoryArithmetic},
+		{[]vm.OpCode{vm.LT, vm.EQ, vm.ISZERO}, CategoryComparison},
+		{[]vm.OpCode{vm.AND, vm.NOT, vm.BYTE, vm.SAR}, CategoryBitwise},
+		{[]vm.OpCode{vm.ADDRESS, vm.CALLDATACOPY, vm.RETURNDATACOPY, vm.GAS}, CategoryEnvironmental},
+		{[]vm.OpCode{vm.BLOCKHASH, vm.GASLIMIT}, CategoryBlock},
+		{[]vm.OpCode{vm.POP}, CategoryStack},
+		{[]vm.OpCode{vm.MLOAD, vm.MSTORE8, vm.MSIZE}, CategoryMemory},
+		{[]vm.OpCode{vm.SLOAD, vm.SSTORE}, CategoryStorage},
+		{[]vm.OpCode{vm.STOP, vm.JUMP, vm.JUMPI, vm.PC, vm.JUMPDEST}, CategoryFlow},
+		{[]vm.OpCode{vm.CREATE, vm.CALL, vm.CREATE2, vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT}, CategorySystem},
+		{[]vm.OpCode{vm.PUSH0, vm.PUSH1, vm.PUSH32}, CategoryPush},
+		{[]vm.OpCode{vm.DUP1, vm.DUP16}, CategoryDup},
+		{[]vm.OpCode{vm.SWAP1, vm.SWAP16}, CategorySwap},
+		{[]vm.OpCode{vm.LOG0, vm.LOG4}, CategoryLog},
+		{[]vm.OpCode{0x0c, 0x21, 0x46, 0xef}, CategoryUnknown},
+	}
+	for _, test := range tests {
+		for _, op := range test.ops {
+			if got := Category(op); got != test.category {
+				t.Errorf("%v: expected %v, but got %v instead.", op, test.category, got)
+			}
+		}
+	}
+	// Every defined opcode is categorized
+	for i := 0; i < 256; i++ {
+		if op := vm.OpCode(i); isDefined(op) && Category(op) == CategoryUnknown {
+			t.Errorf("%v: expected a category, but got none.", op)
+		}
+	}
+}
+
+// Tests pairing disassembled instructions with their category
+func TestDisassembleCategorized(t *testing.T) { log.DebugLog()
+	instrs, err := DisassembleCategorized([]byte{byte(vm.PUSH1), 0x01, byte(vm.SLOAD)})
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if len(instrs) != 2 || instrs[0].Category != CategoryPush || instrs[1].Category != CategoryStorage || instrs[1].PC != 2 {
+		t.Errorf("Expected PUSH1 push and SLOAD storage, but got %+v.", instrs)
+	}
+	if _, err := DisassembleCategorized([]byte{byte(vm.PUSH2)}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests the numeric indices of DUPn, SWAPn and PUSHn
+func TestOpcodeIndices(t *testing.T) { log.DebugLog()
+	for i := 1; i <= 16; i++ {
+		if n, ok := DupIndex(vm.DUP1 + vm.OpCode(i-1)); !ok || n != i {
+			t.Errorf("DUP%d: expected index %d, but got %d (%v).", i, i, n, ok)
+		}
+		if n, ok := SwapIndex(vm.SWAP1 + vm.OpCode(i-1)); !ok || n != i {
+			t.Errorf("SWAP%d: expected index %d, but got %d (%v).", i, i, n, ok)
+		}
+	}
+	for i := 1; i <= 32; i++ {
+		if n, ok := PushWidth(vm.PUSH1 + vm.OpCode(i-1)); !ok || n != i {
+			t.Errorf("PUSH%d: expected width %d, but got %d (%v).", i, i, n, ok)
+		}
+	}
+	if n, ok := PushWidth(vm.PUSH0); !ok || n != 0 {
+		t.Errorf("PUSH0: expected width 0, but got %d (%v).", n, ok)
+	}
+	for _, op := range []vm.OpCode{vm.ADD, vm.PUSH32, vm.LOG0, vm.SWAP1, 0x0c} {
+		if _, ok := DupIndex(op); ok {
+			t.Errorf("%v: expected no DUP index.", op)
+		}
+	}
+	for _, op := range []vm.OpCode{vm.ADD, vm.DUP16, vm.LOG0, 0xa0} {
+		if _, ok := SwapIndex(op); ok {
+			t.Errorf("%v: expected no SWAP index.", op)
+		}
+	}
+	for _, op := range []vm.OpCode{vm.ADD, vm.DUP1, vm.JUMPDEST} {
+		if _, ok := PushWidth(op); ok {
+			t.Errorf("%v: expected no push width.", op)
+		}
+	}
+}
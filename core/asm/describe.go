@@ -0,0 +1,206 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// descriptions holds a short explanation of every opcode that is not part of
+// the numbered PUSH, DUP, SWAP and LOG families.
+var descriptions = map[vm.OpCode]string{
+	vm.STOP:       "halts execution",
+	vm.ADD:        "addition operation",
+	vm.MUL:        "multiplication operation",
+	vm.SUB:        "subtraction operation",
+	vm.DIV:        "integer division operation",
+	vm.SDIV:       "signed integer division operation",
+	vm.MOD:        "modulo remainder operation",
+	vm.SMOD:       "signed modulo remainder operation",
+	vm.ADDMOD:     "modulo addition operation",
+	vm.MULMOD:     "modulo multiplication operation",
+	vm.EXP:        "exponential operation",
+	vm.SIGNEXTEND: "extend length of two's complement signed integer",
+
+	vm.LT:     "less-than comparison",
+	vm.GT:     "greater-than comparison",
+	vm.SLT:    "signed less-than comparison",
+	vm.SGT:    "signed greater-than comparison",
+	vm.EQ:     "equality comparison",
+	vm.ISZERO: "simple not operator",
+	vm.AND:    "bitwise AND operation",
+	vm.OR:     "bitwise OR operation",
+	vm.XOR:    "bitwise XOR operation",
+	vm.NOT:    "bitwise NOT operation",
+	vm.BYTE:   "retrieve single byte from word",
+	vm.SHL:    "left shift operation",
+	vm.SHR:    "logical right shift operation",
+	vm.SAR:    "arithmetic right shift operation",
+	vm.SHA3:   "compute Keccak-256 hash",
+
+	vm.ADDRESS:        "get address of currently executing account",
+	vm.BALANCE:        "get balance of the given account",
+	vm.ORIGIN:         "get execution origination address",
+	vm.CALLER:         "get caller address",
+	vm.CALLVALUE:      "get deposited value by the caller",
+	vm.CALLDATALOAD:   "get input data of current environment",
+	vm.CALLDATASIZE:   "get size of input data in current environment",
+	vm.CALLDATACOPY:   "copy input data in current environment to memory",
+	vm.CODESIZE:       "get size of code running in current environment",
+	vm.CODECOPY:       "copy code running in current environment to memory",
+	vm.GASPRICE:       "get price of gas in current environment",
+	vm.EXTCODESIZE:    "get size of an account's code",
+	vm.EXTCODECOPY:    "copy an account's code to memory",
+	vm.RETURNDATASIZE: "get size of output data from the previous call",
+	vm.RETURNDATACOPY: "copy output data from the previous call to memory",
+
+	vm.BLOCKHASH:  "get the hash of one of the 256 most recent blocks",
+	vm.COINBASE:   "get the block's beneficiary address",
+	vm.TIMESTAMP:  "get the block's timestamp",
+	vm.NUMBER:     "get the block's number",
+	vm.DIFFICULTY: "get the block's difficulty",
+	vm.GASLIMIT:   "get the block's gas limit",
+
+	vm.POP:      "remove item from stack",
+	vm.MLOAD:    "load word from memory",
+	vm.MSTORE:   "save word to memory",
+	vm.MSTORE8:  "save byte to memory",
+	vm.SLOAD:    "load word from storage",
+	vm.SSTORE:   "save word to storage",
+	vm.JUMP:     "alter the program counter",
+	vm.JUMPI:    "conditionally alter the program counter",
+	vm.PC:       "get the value of the program counter",
+	vm.MSIZE:    "get the size of active memory in bytes",
+	vm.GAS:      "get the amount of available gas",
+	vm.JUMPDEST: "mark a valid destination for jumps",
+	vm.PUSH0:    "place the constant zero on stack",
+
+	vm.CREATE:       "create a new account with associated code",
+	vm.CALL:         "message-call into an account",
+	vm.CALLCODE:     "message-call into this account with an alternative account's code",
+	vm.RETURN:       "halt execution returning output data",
+	vm.DELEGATECALL: "message-call into this account with an alternative account's code, persisting the current sender and value",
+	vm.CREATE2:      "create a new account with associated code at a predictable address",
+	vm.STATICCALL:   "static message-call into an account",
+	vm.REVERT:       "halt execution reverting state changes but returning data and remaining gas",
+	vm.INVALID:      "designated invalid instruction",
+	vm.SELFDESTRUCT: "halt execution and register account for later deletion",
+}
+
+// Describe returns a short human-readable description of the opcode, suitable
+// for tooltips. Undefined opcodes are described as such.
+func Describe(op vm.OpCode) string { log.DebugLog()
+	if desc, ok := descriptions[op]; ok {
+		return desc
+	}
+	if n, ok := PushWidth(op); ok {
+		return fmt.Sprintf("place %d byte item on stack", n)
+	}
+	if n, ok := DupIndex(op); ok {
+		return fmt.Sprintf("duplicate %s stack item", ordinal(n))
+	}
+	if n, ok := SwapIndex(op); ok {
+		return fmt.Sprintf("exchange 1st and %s stack items", ordinal(n+1))
+	}
+	if op >= vm.LOG0 && op <= vm.LOG4 {
+		return fmt.Sprintf("append log record with %d topics", op-vm.LOG0)
+	}
+	return "undefined opcode"
+}
+
+// ordinal returns the English ordinal form of n, e.g. 1st or 12th.
+func ordinal(n int) string { log.DebugLog()
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// operands names the stack inputs and outputs of common opcodes, as rendered by
+// DisassembleVerbose.
+var operands = map[vm.OpCode]string{
+	vm.ADD:          "a, b -> a+b",
+	vm.MUL:          "a, b -> a*b",
+	vm.SUB:          "a, b -> a-b",
+	vm.DIV:          "a, b -> a/b",
+	vm.SDIV:         "a, b -> a/b",
+	vm.MOD:          "a, b -> a%b",
+	vm.SMOD:         "a, b -> a%b",
+	vm.ADDMOD:       "a, b, n -> (a+b)%n",
+	vm.MULMOD:       "a, b, n -> (a*b)%n",
+	vm.EXP:          "a, exponent -> a**exponent",
+	vm.LT:           "a, b -> a<b",
+	vm.GT:           "a, b -> a>b",
+	vm.SLT:          "a, b -> a<b",
+	vm.SGT:          "a, b -> a>b",
+	vm.EQ:           "a, b -> a==b",
+	vm.ISZERO:       "a -> a==0",
+	vm.AND:          "a, b -> a&b",
+	vm.OR:           "a, b -> a|b",
+	vm.XOR:          "a, b -> a^b",
+	vm.NOT:          "a -> ~a",
+	vm.BYTE:         "i, x -> x[i]",
+	vm.SHL:          "shift, value -> value<<shift",
+	vm.SHR:          "shift, value -> value>>shift",
+	vm.SAR:          "shift, value -> value>>shift",
+	vm.SHA3:         "offset, size -> hash",
+	vm.BALANCE:      "address -> balance",
+	vm.CALLDATALOAD: "offset -> data",
+	vm.CALLDATACOPY: "destOffset, offset, size ->",
+	vm.CODECOPY:     "destOffset, offset, size ->",
+	vm.EXTCODESIZE:  "address -> size",
+	vm.BLOCKHASH:    "number -> hash",
+	vm.POP:          "a ->",
+	vm.MLOAD:        "offset -> value",
+	vm.MSTORE:       "offset, value ->",
+	vm.MSTORE8:      "offset, value ->",
+	vm.SLOAD:        "key -> value",
+	vm.SSTORE:       "key, value ->",
+	vm.JUMP:         "counter ->",
+	vm.JUMPI:        "counter, condition ->",
+	vm.CREATE:       "value, offset, size -> address",
+	vm.CREATE2:      "value, offset, size, salt -> address",
+	vm.CALL:         "gas, address, value, argsOffset, argsSize, retOffset, retSize -> success",
+	vm.CALLCODE:     "gas, address, value, argsOffset, argsSize, retOffset, retSize -> success",
+	vm.DELEGATECALL: "gas, address, argsOffset, argsSize, retOffset, retSize -> success",
+	vm.STATICCALL:   "gas, address, argsOffset, argsSize, retOffset, retSize -> success",
+	vm.RETURN:       "offset, size ->",
+	vm.REVERT:       "offset, size ->",
+	vm.SELFDESTRUCT: "address ->",
+}
+
+// Return all disassembled EVM instructions in human-readable format, followed
+// by a comment naming the stack inputs and outputs of the opcode where known,
+// e.g. "ADD ; a, b -> a+b".
+func DisassembleVerbose(script []byte) ([]string, error) { log.DebugLog()
+	return DisassembleFormat(script, func(instr Instruction) string {
+		if names, ok := operands[instr.Op]; ok {
+			return fmt.Sprintf("%v ; %s\n", instr, names)
+		}
+		return DefaultFormat(instr)
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests the opcode descriptions
+func TestDescribe(t *testing.T) { log.DebugLog()
+	tests := map[vm.OpCode]string{
+		vm.ADD:    "addition operation",
+		vm.SSTORE: "save word to storage",
+		vm.PUSH1:  "place 1 byte item on stack",
+		vm.PUSH32: "place 32 byte item on stack",
+		vm.DUP2:   "duplicate 2nd stack item",
+		vm.DUP11:  "duplicate 11th stack item",
+		vm.SWAP1:  "exchange 1st and 2nd stack items",
+		vm.SWAP2:  "exchange 1st and 3rd stack items",
+		vm.SWAP16: "exchange 1st and 17th stack items",
+		vm.LOG3:   "append log record with 3 topics",
+		0x0c:      "undefined opcode",
+	}
+	for op, want := range tests {
+		if got := Describe(op); got != want {
+			t.Errorf("%v: expected %q, but got %q instead.", op, want, got)
+		}
+	}
+	// Every categorized opcode is described
+	for i := 0; i < 256; i++ {
+		if op := vm.OpCode(i); Category(op) != CategoryUnknown && Describe(op) == "undefined opcode" {
+			t.Errorf("%v: expected a description, but got none.", op)
+		}
+	}
+}
+
+// Tests annotating instructions with their operand names
+func TestDisassembleVerbose(t *testing.T) { log.DebugLog()
+	instrs, err := DisassembleVerbose([]byte{byte(vm.PUSH1), 0x01, byte(vm.DUP1), byte(vm.ADD), byte(vm.PUSH0), byte(vm.SSTORE)})
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{
+		"000000: PUSH1 0x01\n",
+		"000002: DUP1\n",
+		"000003: ADD ; a, b -> a+b\n",
+		"000004: PUSH0\n",
+		"000005: SSTORE ; key, value ->\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+	// The operand names agree with the stack effects
+	for op, names := range operands {
+		pops, pushes := stackEffect(op)
+		parts := strings.SplitN(names, "->", 2)
+		inputs := len(strings.Split(strings.TrimSpace(parts[0]), ","))
+		outputs := 0
+		if strings.TrimSpace(parts[1]) != "" {
+			outputs = 1
+		}
+		if inputs != pops || outputs != pushes {
+			t.Errorf("%v: expected %d inputs and %d outputs, but %q names %d and %d.", op, pops, pushes, names, inputs, outputs)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// DiffKind classifies an entry of an instruction-level diff.
+type DiffKind int
+
+const (
+	DiffEqual   DiffKind = iota // Instruction present in both programs
+	DiffAdded                   // Instruction only present in the new program
+	DiffRemoved                 // Instruction only present in the old program
+	DiffChanged                 // Same opcode with a different argument
+)
+
+func (k DiffKind) String() string { log.DebugLog()
+	switch k {
+	case DiffEqual:
+		return "equal"
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// DiffEntry is a single entry of an instruction-level diff. Old is unset for
+// added instructions and New is unset for removed ones.
+type DiffEntry struct {
+	Kind DiffKind
+	Old  Instruction // Instruction in the old program, including its PC
+	New  Instruction // Instruction in the new program, including its PC
+}
+
+// Diff compares two programs instruction by instruction, aligning them along
+// their longest common subsequence of opcodes and arguments. Aligned
+// instructions sharing the opcode but differing in argument are reported as
+// changed.
+// Solidity metadata trailers are stripped from both programs beforehand. The
+// alignment uses Hirschberg's algorithm, so memory stays linear in the size of
+// the programs.
+func Diff(a, b []byte) ([]DiffEntry, error) { log.DebugLog()
+	before, err := DisassembleInstructions(stripMetadata(a))
+	if err != nil {
+		return nil, err
+	}
+	after, err := DisassembleInstructions(stripMetadata(b))
+	if err != nil {
+		return nil, err
+	}
+	// Trim the shared prefix and suffix, usually the bulk of an upgrade
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix].Equal(after[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix && before[len(before)-1-suffix].Equal(after[len(after)-1-suffix]) {
+		suffix++
+	}
+	entries := make([]DiffEntry, 0, len(before)+len(after)-prefix-suffix)
+	for k := 0; k < prefix; k++ {
+		entries = append(entries, DiffEntry{Kind: DiffEqual, Old: before[k], New: after[k]})
+	}
+	oldMid, newMid := before[prefix:len(before)-suffix], after[prefix:len(after)-suffix]
+
+	i, j := 0, 0
+	for _, m := range alignInstructions(oldMid, newMid, 0, 0, nil) {
+		entries = appendDiffGap(entries, oldMid[i:m[0]], newMid[j:m[1]])
+		entries = append(entries, DiffEntry{Kind: DiffEqual, Old: oldMid[m[0]], New: newMid[m[1]]})
+		i, j = m[0]+1, m[1]+1
+	}
+	entries = appendDiffGap(entries, oldMid[i:], newMid[j:])
+
+	for k := suffix; k > 0; k-- {
+		entries = append(entries, DiffEntry{Kind: DiffEqual, Old: before[len(before)-k], New: after[len(after)-k]})
+	}
+	return entries, nil
+}
+
+// appendDiffGap appends the entries for a stretch of instructions between two
+// aligned ones. Leading instructions sharing their opcode are paired up as
+// changed, the rest are reported as removed followed by added.
+func appendDiffGap(entries []DiffEntry, removed, added []Instruction) []DiffEntry { log.DebugLog()
+	k := 0
+	for ; k < len(removed) && k < len(added) && removed[k].EqualIgnoringArg(added[k]); k++ {
+		entries = append(entries, DiffEntry{Kind: DiffChanged, Old: removed[k], New: added[k]})
+	}
+	for _, instr := range removed[k:] {
+		entries = append(entries, DiffEntry{Kind: DiffRemoved, Old: instr})
+	}
+	for _, instr := range added[k:] {
+		entries = append(entries, DiffEntry{Kind: DiffAdded, New: instr})
+	}
+	return entries
+}
+
+// alignInstructions appends the index pairs of a longest common subsequence
+// of a and b to matches, in order, offsetting them by offA and offB.
+func alignInstructions(a, b []Instruction, offA, offB int, matches [][2]int) [][2]int { log.DebugLog()
+	if len(a) == 0 || len(b) == 0 {
+		return matches
+	}
+	if len(a) == 1 {
+		for j := range b {
+			if a[0].Equal(b[j]) {
+				return append(matches, [2]int{offA, offB + j})
+			}
+		}
+		return matches
+	}
+	// Split b where the halves of a contribute most to the common subsequence
+	mid := len(a) / 2
+	fwd := lcsLengths(a[:mid], b, false)
+	bwd := lcsLengths(a[mid:], b, true)
+
+	split, best := 0, -1
+	for j := range fwd {
+		if fwd[j]+bwd[j] > best {
+			split, best = j, fwd[j]+bwd[j]
+		}
+	}
+	matches = alignInstructions(a[:mid], b[:split], offA, offB, matches)
+	return alignInstructions(a[mid:], b[split:], offA+mid, offB+split, matches)
+}
+
+// lcsLengths returns for every j the length of the longest common subsequence
+// of a and b[:j], or of a and b[j:] if reverse is set. This is the hot loop of
+// Diff, so instructions are compared inline.
+func lcsLengths(a, b []Instruction, reverse bool) []int { log.DebugLog()
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		if reverse {
+			instr := a[len(a)-1-i]
+			for j := len(b) - 1; j >= 0; j-- {
+				switch {
+				case instr.Op == b[j].Op && bytes.Equal(instr.Arg, b[j].Arg):
+					cur[j] = prev[j+1] + 1
+				case prev[j] >= cur[j+1]:
+					cur[j] = prev[j]
+				default:
+					cur[j] = cur[j+1]
+				}
+			}
+		} else {
+			for j := 1; j <= len(b); j++ {
+				switch {
+				case a[i].Op == b[j-1].Op && bytes.Equal(a[i].Arg, b[j-1].Arg):
+					cur[j] = prev[j-1] + 1
+				case prev[j] >= cur[j-1]:
+					cur[j] = prev[j]
+				default:
+					cur[j] = cur[j-1]
+				}
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
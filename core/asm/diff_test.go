@@ -0,0 +1,132 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests the instruction-level diff of two programs
+func TestDiff(t *testing.T) { log.DebugLog()
+	// PUSH1 0x01 PUSH1 0x02 ADD POP STOP
+	a, _ := hex.DecodeString("600160020150" + "00")
+	// PUSH1 0x01 PUSH1 0x03 ADD CALLER STOP
+	b, _ := hex.DecodeString("600160030133" + "00")
+
+	entries, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []struct {
+		kind  DiffKind
+		oldPC uint64
+		newPC uint64
+	}{
+		{DiffEqual, 0, 0},
+		{DiffChanged, 2, 2},
+		{DiffEqual, 4, 4},
+		{DiffRemoved, 5, 0},
+		{DiffAdded, 0, 5},
+		{DiffEqual, 6, 6},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, but got %d: %+v.", len(want), len(entries), entries)
+	}
+	for i, w := range want {
+		if e := entries[i]; e.Kind != w.kind || e.Old.PC != w.oldPC || e.New.PC != w.newPC {
+			t.Errorf("entry %d: expected %v %d/%d, but got %v %d/%d.", i, w.kind, w.oldPC, w.newPC, e.Kind, e.Old.PC, e.New.PC)
+		}
+	}
+	if _, err := Diff(a, []byte{0x61}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests that the alignment is a longest common subsequence covering both
+// programs in order
+func TestDiffAlignment(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		a, b string
+		lcs  int
+	}{
+		{"", "", 0},
+		{"0102030405", "", 0},
+		{"", "0102030405", 0},
+		{"0102030405", "0102030405", 5},
+		{"0102030405", "0506070809", 1},
+		{"0a010b020c030d", "01020304", 3},
+		{"33343536373839", "39383736353433", 1},
+		{"01600102600303", "0360010160020303", 2},
+	}
+	for _, test := range tests {
+		a, _ := hex.DecodeString(test.a)
+		b, _ := hex.DecodeString(test.b)
+		entries, err := Diff(a, b)
+		if err != nil {
+			t.Errorf("%s/%s: expected no error, but encountered %v instead.", test.a, test.b, err)
+			continue
+		}
+		var oldCode, newCode []byte
+		equal := 0
+		for _, e := range entries {
+			if e.Kind != DiffAdded {
+				enc, _ := EncodeInstruction(e.Old.Op, e.Old.Arg)
+				oldCode = append(oldCode, enc...)
+			}
+			if e.Kind != DiffRemoved {
+				enc, _ := EncodeInstruction(e.New.Op, e.New.Arg)
+				newCode = append(newCode, enc...)
+			}
+			if e.Kind == DiffEqual {
+				equal++
+			}
+		}
+		if !bytes.Equal(oldCode, a) || !bytes.Equal(newCode, b) {
+			t.Errorf("%s/%s: entries cover %x/%x instead.", test.a, test.b, oldCode, newCode)
+		}
+		if equal != test.lcs {
+			t.Errorf("%s/%s: expected %d equal entries, but got %d.", test.a, test.b, test.lcs, equal)
+		}
+	}
+}
+
+// Tests diffing programs at the contract size limit
+func TestDiffLarge(t *testing.T) { log.DebugLog()
+	a := bytes.Repeat([]byte{byte(vm.JUMPDEST)}, 24576)
+	b := append([]byte{}, a...)
+	for pc := 10000; pc < 14000; pc += 100 {
+		b[pc] = byte(vm.CALLER)
+	}
+	entries, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	changed := 0
+	for _, e := range entries {
+		if e.Kind != DiffEqual {
+			changed++
+		}
+	}
+	if changed != 80 {
+		t.Errorf("Expected 80 differing entries, but got %d.", changed)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// EOFSectionKind identifies the kind of a section in an EOF container.
+type EOFSectionKind byte
+
+const (
+	EOFTypeSection EOFSectionKind = 0x01
+	EOFCodeSection EOFSectionKind = 0x02
+	EOFDataSection EOFSectionKind = 0x04
+)
+
+const (
+	eofMagic      = 0xef00
+	eofVersion    = 0x01
+	eofTerminator = 0x00
+)
+
+// ErrNotEOF is returned when the input does not start with the EOF magic, in
+// which case it should be treated as legacy code.
+var ErrNotEOF = errors.New("not an EOF container")
+
+// EOFSection is a single section of an EOF (EIP-3540) container.
+type EOFSection struct {
+	Kind         EOFSectionKind
+	Offset       uint64   // offset of the section body within the container
+	Data         []byte   // raw section body
+	Instructions []string // disassembly, only set for code sections
+}
+
+// DisassembleEOF parses an EOF container and disassembles each of its code
+// sections independently, with PCs relative to the start of the section.
+// Immediates of EOF-only instructions are not known to the decoder and show
+// up as separate instructions. ErrNotEOF is returned for legacy code.
+func DisassembleEOF(container []byte) ([]EOFSection, error) { log.DebugLog()
+	if len(container) < 3 || binary.BigEndian.Uint16(container) != eofMagic {
+		return nil, ErrNotEOF
+	}
+	if container[2] != eofVersion {
+		return nil, fmt.Errorf("unsupported EOF version %d", container[2])
+	}
+	pos := 3
+	read := func(n int) ([]byte, error) {
+		if len(container) < pos+n {
+			return nil, fmt.Errorf("truncated EOF header at %d", pos)
+		}
+		b := container[pos : pos+n]
+		pos += n
+		return b, nil
+	}
+	readSize := func() (int, error) {
+		b, err := read(2)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint16(b)), nil
+	}
+	// Parse the header: the type, code and data sections must appear in order.
+	var (
+		kinds []EOFSectionKind
+		sizes []int
+	)
+	for _, want := range []EOFSectionKind{EOFTypeSection, EOFCodeSection, EOFDataSection} {
+		b, err := read(1)
+		if err != nil {
+			return nil, err
+		}
+		if EOFSectionKind(b[0]) != want {
+			return nil, fmt.Errorf("unexpected EOF section kind %#x at %d, want %#x", b[0], pos-1, byte(want))
+		}
+		count := 1
+		if want == EOFCodeSection {
+			if count, err = readSize(); err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return nil, errors.New("EOF container without code sections")
+			}
+		}
+		for i := 0; i < count; i++ {
+			size, err := readSize()
+			if err != nil {
+				return nil, err
+			}
+			kinds = append(kinds, want)
+			sizes = append(sizes, size)
+		}
+	}
+	if b, err := read(1); err != nil {
+		return nil, err
+	} else if b[0] != eofTerminator {
+		return nil, fmt.Errorf("missing EOF header terminator at %d", pos-1)
+	}
+	// Slice up the body according to the header
+	sections := make([]EOFSection, 0, len(kinds))
+	for i, kind := range kinds {
+		offset := pos
+		body, err := read(sizes[i])
+		if err != nil {
+			return nil, fmt.Errorf("EOF section %d exceeds container", i)
+		}
+		section := EOFSection{Kind: kind, Offset: uint64(offset), Data: body}
+		if kind == EOFCodeSection {
+			if section.Instructions, err = Disassemble(body); err != nil {
+				return nil, fmt.Errorf("EOF code section %d: %v", i, err)
+			}
+		}
+		sections = append(sections, section)
+	}
+	if pos != len(container) {
+		return nil, fmt.Errorf("%d trailing bytes after EOF container", len(container)-pos)
+	}
+	return sections, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests parsing and disassembling an EOF container
+func TestDisassembleEOF(t *testing.T) { log.DebugLog()
+	// Header: magic, version, one type section of 8 bytes, two code sections
+	// of 3 and 1 bytes, a data section of 2 bytes and the terminator.
+	container, _ := hex.DecodeString("ef0001" + "010008" + "0200020003" + "0001" + "040002" + "00" +
+		"0000008000000000" + "600100" + "00" + "aabb")
+
+	sections, err := DisassembleEOF(container)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	kinds := make([]EOFSectionKind, len(sections))
+	for i, section := range sections {
+		kinds[i] = section.Kind
+	}
+	if want := []EOFSectionKind{EOFTypeSection, EOFCodeSection, EOFCodeSection, EOFDataSection}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("Expected %v, but got %v instead.", want, kinds)
+	}
+	if want := []string{"000000: PUSH1 0x01\n", "000002: STOP\n"}; !reflect.DeepEqual(sections[1].Instructions, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, sections[1].Instructions)
+	}
+	if sections[3].Offset != 29 || hex.EncodeToString(sections[3].Data) != "aabb" {
+		t.Errorf("Expected data aabb at 29, but got %x at %d.", sections[3].Data, sections[3].Offset)
+	}
+}
+
+// Tests that malformed and legacy inputs are rejected
+func TestDisassembleEOFErrors(t *testing.T) { log.DebugLog()
+	if _, err := DisassembleEOF([]byte{0x60, 0x80}); err != ErrNotEOF {
+		t.Errorf("Expected %v, but got %v instead.", ErrNotEOF, err)
+	}
+	for _, code := range []string{
+		"ef0002",       // unknown version
+		"ef0001010004", // truncated header
+		"ef00010100040200010001040000010000000000",   // bad terminator
+		"ef0001010004020001000104000000000000000000", // trailing byte
+		"ef000101000402000100010400000000000000",     // body too short
+		"ef000102000101000404000000000000000000",     // sections out of order
+	} {
+		container, _ := hex.DecodeString(code)
+		if _, err := DisassembleEOF(container); err == nil || err == ErrNotEOF {
+			t.Errorf("container %s: expected a parse error, but got %v.", code, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Fork identifies the EVM rule set used to decode and validate opcodes.
+type Fork int
+
+const (
+	Frontier Fork = iota
+	Homestead
+	Byzantium
+	Constantinople
+	Shanghai
+
+	// Latest is the most recent fork known to the package.
+	Latest = Shanghai
+)
+
+// opcodeForks maps the opcodes introduced after Frontier to the fork that
+// activated them.
+var opcodeForks = map[vm.OpCode]Fork{
+	vm.DELEGATECALL:   Homestead,
+	vm.STATICCALL:     Byzantium,
+	vm.RETURNDATASIZE: Byzantium,
+	vm.RETURNDATACOPY: Byzantium,
+	vm.REVERT:         Byzantium,
+	vm.SHL:            Constantinople,
+	vm.SHR:            Constantinople,
+	vm.SAR:            Constantinople,
+	vm.CREATE2:        Constantinople,
+	vm.PUSH0:          Shanghai,
+}
+
+// defines returns whether op is a valid instruction in the fork.
+func (f Fork) defines(op vm.OpCode) bool { log.DebugLog()
+	if !isDefined(op) {
+		return false
+	}
+	if since, ok := opcodeForks[op]; ok {
+		return since <= f
+	}
+	return true
+}
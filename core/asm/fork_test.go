@@ -0,0 +1,55 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that opcode validity follows the selected fork
+func TestInstructionIteratorWithFork(t *testing.T) { log.DebugLog()
+	// PUSH0 SHL REVERT DELEGATECALL
+	script, _ := hex.DecodeString("5f1bfdf4")
+
+	tests := []struct {
+		fork  Fork
+		valid []bool
+	}{
+		{Frontier, []bool{false, false, false, false}},
+		{Homestead, []bool{false, false, false, true}},
+		{Byzantium, []bool{false, false, true, true}},
+		{Constantinople, []bool{false, true, true, true}},
+		{Shanghai, []bool{true, true, true, true}},
+	}
+	for _, test := range tests {
+		var valid []bool
+		it := NewInstructionIteratorWithFork(script, test.fork)
+		for it.Next() {
+			valid = append(valid, it.IsValidOp())
+		}
+		if err := it.Error(); err != nil {
+			t.Errorf("fork %d: expected no error, but encountered %v instead.", test.fork, err)
+		}
+		if !reflect.DeepEqual(valid, test.valid) {
+			t.Errorf("fork %d: expected %v, but got %v instead.", test.fork, test.valid, valid)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// staticGas returns the constant portion of the gas charged for op by the
+// latest instruction set in core/vm. Operations with a dynamic component
+// (memory expansion, copied words, storage changes, calls) only report their
+// fixed base cost, so the value is the static minimum rather than the actual
+// charge. Undefined opcodes cost nothing.
+func staticGas(op vm.OpCode) uint64 { log.DebugLog()
+	switch {
+	case op.IsPush() || op >= vm.DUP1 && op <= vm.SWAP16:
+		return vm.GasFastestStep
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return params.LogGas + uint64(op-vm.LOG0)*params.LogTopicGas
+	}
+	gt := params.GasTableEIP158
+	switch op {
+	case vm.ADDRESS, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATASIZE, vm.CODESIZE,
+		vm.GASPRICE, vm.RETURNDATASIZE, vm.COINBASE, vm.TIMESTAMP, vm.NUMBER,
+		vm.DIFFICULTY, vm.GASLIMIT, vm.POP, vm.PC, vm.MSIZE, vm.GAS, vm.PUSH0:
+		return vm.GasQuickStep
+	case vm.ADD, vm.SUB, vm.LT, vm.GT, vm.SLT, vm.SGT, vm.EQ, vm.ISZERO, vm.AND, vm.OR,
+		vm.XOR, vm.NOT, vm.BYTE, vm.SHL, vm.SHR, vm.SAR, vm.CALLDATALOAD, vm.MLOAD,
+		vm.MSTORE, vm.MSTORE8, vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		return vm.GasFastestStep
+	case vm.MUL, vm.DIV, vm.SDIV, vm.MOD, vm.SMOD, vm.SIGNEXTEND:
+		return vm.GasFastStep
+	case vm.ADDMOD, vm.MULMOD, vm.JUMP:
+		return vm.GasMidStep
+	case vm.JUMPI:
+		return vm.GasSlowStep
+	case vm.BLOCKHASH:
+		return vm.GasExtStep
+	case vm.JUMPDEST:
+		return params.JumpdestGas
+	case vm.EXP:
+		return params.ExpGas
+	case vm.SHA3:
+		return params.Sha3Gas
+	case vm.BALANCE:
+		return gt.Balance
+	case vm.EXTCODESIZE:
+		return gt.ExtcodeSize
+	case vm.EXTCODECOPY:
+		return gt.ExtcodeCopy
+	case vm.SLOAD:
+		return gt.SLoad
+	case vm.SSTORE:
+		return params.SstoreResetGas
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		return gt.Calls
+	case vm.SELFDESTRUCT:
+		return gt.Suicide
+	case vm.CREATE, vm.CREATE2:
+		return params.CreateGas
+	}
+	return 0
+}
+
+// Return all disassembled EVM instructions in human-readable format, each
+// annotated with the static gas cost of its opcode. Operations with dynamic
+// costs report their static minimum.
+func DisassembleWithGas(script []byte) ([]string, error) { log.DebugLog()
+	instrs := make([]string, 0, len(script)/2)
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		instrs = append(instrs, fmt.Sprintf("%v (gas %d)\n", it.current(), staticGas(it.Op())))
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests the gas annotated disassembly output
+func TestDisassembleWithGas(t *testing.T) { log.DebugLog()
+	// PUSH1 0x60 PUSH1 0x40 MSTORE SLOAD JUMPDEST LOG2 STOP
+	script, _ := hex.DecodeString("6060604052545ba200")
+
+	instrs, err := DisassembleWithGas(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{
+		"000000: PUSH1 0x60 (gas 3)\n",
+		"000002: PUSH1 0x40 (gas 3)\n",
+		"000004: MSTORE (gas 3)\n",
+		"000005: SLOAD (gas 200)\n",
+		"000006: JUMPDEST (gas 1)\n",
+		"000007: LOG2 (gas 1125)\n",
+		"000008: STOP (gas 0)\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build go1.23
+// +build go1.23
+
+package asm
+
+import (
+	"iter"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Instructions returns a range-over-func sequence of the PCs and instructions
+// in the code. Decoding stops silently at a truncated push, use
+// InstructionsWithError to detect it.
+func Instructions(script []byte) iter.Seq2[uint64, Instruction] { log.DebugLog()
+	seq, _ := InstructionsWithError(script)
+	return seq
+}
+
+// InstructionsWithError is like Instructions, but also returns a function
+// reporting the error that terminated the last iteration over the sequence,
+// if any. It must only be called once the range loop is done.
+func InstructionsWithError(script []byte) (iter.Seq2[uint64, Instruction], func() error) { log.DebugLog()
+	var err error
+
+	seq := func(yield func(uint64, Instruction) bool) {
+		it := NewInstructionIterator(script)
+		for it.Next() {
+			if !yield(it.PC(), it.Instruction()) {
+				break
+			}
+		}
+		err = it.Error()
+	}
+	return seq, func() error { return err }
+}
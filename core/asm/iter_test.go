@@ -0,0 +1,56 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build go1.23
+// +build go1.23
+
+package asm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests ranging over the instructions of the code
+func TestInstructions(t *testing.T) { log.DebugLog()
+	var pcs []uint64
+	for pc, instr := range Instructions([]byte{byte(vm.PUSH1), 0x01, byte(vm.ADD)}) {
+		if pc != instr.PC {
+			t.Errorf("Expected pc %d to match instruction pc %d.", pc, instr.PC)
+		}
+		pcs = append(pcs, pc)
+	}
+	if len(pcs) != 2 || pcs[0] != 0 || pcs[1] != 2 {
+		t.Errorf("Expected pcs [0 2], but got %v instead.", pcs)
+	}
+
+	seq, errFn := InstructionsWithError([]byte{byte(vm.ADD), byte(vm.PUSH2), 0x01})
+	cnt := 0
+	for range seq {
+		cnt++
+	}
+	if cnt != 1 || errFn() == nil {
+		t.Errorf("Expected 1 instruction and an error, but got %d and %v.", cnt, errFn())
+	}
+	for range seq {
+		break
+	}
+	if err := errFn(); err != nil {
+		t.Errorf("Expected no error after early break, but got %v.", err)
+	}
+}
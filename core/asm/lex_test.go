@@ -19,6 +19,8 @@ package asm
 import (
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func lexAll(src string) []token { log.DebugLog()
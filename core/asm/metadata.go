@@ -0,0 +1,117 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Keys the Solidity compiler puts first in its CBOR metadata map, each with
+// its CBOR text string header.
+var metadataKeys = [][]byte{
+	append([]byte{0x64}, "ipfs"...),
+	append([]byte{0x65}, "bzzr0"...),
+	append([]byte{0x65}, "bzzr1"...),
+	append([]byte{0x64}, "solc"...),
+}
+
+// metadataLength returns the size of the CBOR metadata trailer appended by the
+// Solidity compiler, including its 2 byte big-endian length suffix. Zero is
+// returned unless the code ends in a CBOR map starting with a known key, so
+// that ordinary code is never cut short.
+func metadataLength(script []byte) int { log.DebugLog()
+	if len(script) < 2 {
+		return 0
+	}
+	n := int(script[len(script)-2])<<8 | int(script[len(script)-1])
+	if n == 0 || n+2 > len(script) {
+		return 0
+	}
+	trailer := script[len(script)-n-2 : len(script)-2]
+	if trailer[0] < 0xa1 || trailer[0] > 0xb7 {
+		return 0
+	}
+	for _, key := range metadataKeys {
+		if bytes.HasPrefix(trailer[1:], key) {
+			return n + 2
+		}
+	}
+	return 0
+}
+
+// stripMetadata returns the code without its Solidity metadata trailer. If no
+// plausible trailer is found, the code is returned unchanged.
+func stripMetadata(script []byte) []byte { log.DebugLog()
+	return script[:len(script)-metadataLength(script)]
+}
+
+// ExtractMetadata returns the raw CBOR encoded metadata the Solidity compiler
+// appended to the code, without its length suffix. The CBOR is not decoded. If
+// the code does not end in a plausible trailer, ok is false.
+func ExtractMetadata(script []byte) (cbor []byte, ok bool) { log.DebugLog()
+	n := metadataLength(script)
+	if n == 0 {
+		return nil, false
+	}
+	return script[len(script)-n : len(script)-2], true
+}
+
+// Return all disassembled EVM instructions in human-readable format, skipping
+// the Solidity metadata trailer at the end of the code if there is one.
+func DisassembleCode(script []byte) ([]string, error) { log.DebugLog()
+	return Disassemble(stripMetadata(script))
+}
+
+// Minimum number of consecutive undefined or INVALID instructions considered
+// to be the start of the data section by DisassembleCodeSection.
+const dataSectionMinRun = 8
+
+// Return the disassembled EVM instructions of the code section only, which ends
+// at the Solidity metadata trailer or at the first run of undefined or INVALID
+// opcodes long enough to be data, whichever comes first. The offset of that
+// boundary is returned as well, so the data after it can be dumped.
+func DisassembleCodeSection(script []byte) ([]string, uint64, error) { log.DebugLog()
+	code := stripMetadata(script)
+	boundary := uint64(len(code))
+
+	regions, err := DataRegions(code, dataSectionMinRun)
+	if len(regions) > 0 {
+		boundary = regions[0].Start
+	} else if err != nil {
+		return nil, 0, err
+	}
+	instrs, err := Disassemble(code[:boundary])
+	if err != nil {
+		return nil, 0, err
+	}
+	return instrs, boundary, nil
+}
+
+// CodeHashNoMetadata returns the keccak256 hash of the code without its
+// Solidity metadata trailer, which stays the same when identical sources are
+// recompiled with different metadata. The remaining code must be well-formed.
+func CodeHashNoMetadata(script []byte) (common.Hash, error) { log.DebugLog()
+	code := stripMetadata(script)
+	if err := Validate(code); err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(code), nil
+}
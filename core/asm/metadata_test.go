@@ -0,0 +1,132 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that the metadata trailer is skipped when disassembling
+func TestDisassembleCode(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		code string
+		want int
+	}{
+		// PUSH1 0x80 STOP followed by a 9 byte {"ipfs": h'b2c3'} trailer and its length
+		{"608000a1646970667342b2c30009", 2},
+		// Trailer length larger than the code, keep everything
+		{"6080000100", 4},
+		// Zero length, nothing to strip
+		{"60800000", 3},
+		// Code ending in PUSH1 0x00 RETURN is not a trailer
+		{strings.Repeat("5b", 250) + "6000f3", 252},
+		// A CBOR map without a known key is not a trailer either
+		{"608000a1b2c30003", 7},
+	}
+	for _, test := range tests {
+		script, _ := hex.DecodeString(test.code)
+		instrs, err := DisassembleCode(script)
+		if err != nil {
+			t.Errorf("code %s: expected no error, but encountered %v instead.", test.code, err)
+			continue
+		}
+		if len(instrs) != test.want {
+			t.Errorf("code %s: expected %d instructions, but got %d instead.", test.code, test.want, len(instrs))
+		}
+	}
+}
+
+// Tests extracting the raw metadata trailer
+func TestExtractMetadata(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("608000a1646970667342b2c30009")
+	cbor, ok := ExtractMetadata(script)
+	if want, _ := hex.DecodeString("a1646970667342b2c3"); !ok || !bytes.Equal(cbor, want) {
+		t.Errorf("Expected %x, but got %x (%v) instead.", want, cbor, ok)
+	}
+	for _, code := range []string{"", "00", "6080000100", "60800000", "608000a1b2c30003", strings.Repeat("5b", 250) + "6000f3"} {
+		script, _ := hex.DecodeString(code)
+		if cbor, ok := ExtractMetadata(script); ok {
+			t.Errorf("code %s: expected no metadata, but got %x.", code, cbor)
+		}
+	}
+}
+
+// Tests locating the end of the code section
+func TestDisassembleCodeSection(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		code     string
+		want     int
+		boundary uint64
+	}{
+		// PUSH1 0x80 STOP, a 9 byte trailer and its length
+		{"608000a1646970667342b2c30009", 2, 3},
+		// PUSH1 0x80 STOP, 8 undefined bytes and a truncated push
+		{"608000" + "0c0d0e0f21222324" + "7f", 2, 3},
+		// PUSH1 0x80 STOP, too few undefined bytes to be data
+		{"608000" + "fefe" + "00", 5, 6},
+	}
+	for _, test := range tests {
+		script, _ := hex.DecodeString(test.code)
+		instrs, boundary, err := DisassembleCodeSection(script)
+		if err != nil {
+			t.Errorf("code %s: expected no error, but encountered %v instead.", test.code, err)
+			continue
+		}
+		if len(instrs) != test.want || boundary != test.boundary {
+			t.Errorf("code %s: expected %d instructions up to %d, but got %d up to %d.", test.code, test.want, test.boundary, len(instrs), boundary)
+		}
+	}
+	if _, _, err := DisassembleCodeSection([]byte{0x61, 0x01}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
+
+// Tests that the code hash ignores the metadata trailer
+func TestCodeHashNoMetadata(t *testing.T) { log.DebugLog()
+	a, _ := hex.DecodeString("608000a1646970667342b2c30009")
+	b, _ := hex.DecodeString("608000a1646970667342d4e50009")
+
+	hashA, err := CodeHashNoMetadata(a)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if hashB, _ := CodeHashNoMetadata(b); hashA != hashB {
+		t.Errorf("Expected equal hashes, but got %x and %x.", hashA, hashB)
+	}
+	if want := crypto.Keccak256Hash([]byte{0x60, 0x80, 0x00}); hashA != want {
+		t.Errorf("Expected %x, but got %x instead.", want, hashA)
+	}
+	if _, err := CodeHashNoMetadata([]byte{0x62, 0x00}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+	// Programs differing only in a tail that looks like a length suffix
+	c, _ := hex.DecodeString(strings.Repeat("5b", 250) + "6000f3")
+	d, _ := hex.DecodeString(strings.Repeat("5b", 248) + "6001" + "6000f3")
+	hashC, err := CodeHashNoMetadata(c)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if hashD, _ := CodeHashNoMetadata(d); hashC == hashD {
+		t.Errorf("Expected different hashes, but both are %x.", hashC)
+	}
+}
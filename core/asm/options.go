@@ -0,0 +1,61 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Options configures DisassembleOpts. The zero value disassembles exactly like
+// Disassemble.
+type Options struct {
+	StripMetadata   bool   // Skip the Solidity metadata trailer, see DisassembleCode
+	StartPC         uint64 // Offset to start decoding at, see DisassembleFrom
+	MaxInstructions int    // Limit of instructions to decode if positive, see DisassembleLimit
+	Lenient         bool   // Yield truncated pushes instead of failing, see NewInstructionIteratorStrict
+	PCWidth         int    // Digits to zero-pad PCs to if positive, DefaultPCWidth otherwise
+}
+
+// Return all disassembled EVM instructions in human-readable format, combining
+// the behaviour of the specialised Disassemble variants as selected by opts.
+func DisassembleOpts(script []byte, opts Options) ([]string, error) { log.DebugLog()
+	if opts.StripMetadata {
+		script = stripMetadata(script)
+	}
+	size := len(script) / 2
+	if opts.MaxInstructions > 0 && opts.MaxInstructions < size {
+		size = opts.MaxInstructions
+	}
+	instrs := make([]string, 0, size)
+
+	width := DefaultPCWidth
+	if opts.PCWidth > 0 {
+		width = opts.PCWidth
+	}
+	it := NewInstructionIteratorStrict(script, opts.Lenient)
+	it.pc = opts.StartPC
+	for it.Next() {
+		if opts.MaxInstructions > 0 && len(instrs) >= opts.MaxInstructions {
+			return instrs, ErrTooManyInstructions
+		}
+		instrs = append(instrs, it.current().format(width)+"\n")
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
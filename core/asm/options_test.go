@@ -0,0 +1,65 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that the options compose the specialised disassemblers
+func TestDisassembleOpts(t *testing.T) { log.DebugLog()
+	// PUSH1 0x80 ADD STOP followed by a 9 byte trailer and its length
+	script, _ := hex.DecodeString("60800100a1646970667342b2c30009")
+
+	tests := []struct {
+		opts Options
+		want []string
+		err  error
+	}{
+		{Options{StripMetadata: true}, []string{"000000: PUSH1 0x80\n", "000002: ADD\n", "000003: STOP\n"}, nil},
+		{Options{StripMetadata: true, StartPC: 2}, []string{"000002: ADD\n", "000003: STOP\n"}, nil},
+		{Options{StripMetadata: true, MaxInstructions: 1}, []string{"000000: PUSH1 0x80\n"}, ErrTooManyInstructions},
+		{Options{StartPC: 13, Lenient: true}, []string{"000013: STOP\n", "000014: MULMOD\n"}, nil},
+		{Options{StripMetadata: true, PCWidth: 2}, []string{"00: PUSH1 0x80\n", "02: ADD\n", "03: STOP\n"}, nil},
+		{Options{StripMetadata: true, StartPC: 3, PCWidth: 8}, []string{"00000003: STOP\n"}, nil},
+	}
+	for i, test := range tests {
+		instrs, err := DisassembleOpts(script, test.opts)
+		if err != test.err {
+			t.Errorf("test %d: expected error %v, but got %v instead.", i, test.err, err)
+		}
+		if !reflect.DeepEqual(instrs, test.want) {
+			t.Errorf("test %d: expected %q, but got %q instead.", i, test.want, instrs)
+		}
+	}
+	// The zero value matches Disassemble, including its failures
+	want, _ := Disassemble(script)
+	if instrs, err := DisassembleOpts(script, Options{}); err != nil || !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q (%v) instead.", want, instrs, err)
+	}
+	truncated := []byte{0x61, 0x01}
+	if _, err := DisassembleOpts(truncated, Options{}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+	if instrs, err := DisassembleOpts(truncated, Options{Lenient: true}); err != nil || len(instrs) != 1 {
+		t.Errorf("Expected 1 lenient instruction, but got %q (%v).", instrs, err)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// iteratorPool recycles instruction iterators to reduce allocations.
+var iteratorPool = sync.Pool{
+	New: func() interface{} { return new(instructionIterator) },
+}
+
+// Returns an instruction iterator for the latest fork from a shared pool,
+// behaving like one created by NewInstructionIterator. It should be handed
+// back with ReleaseIterator once done.
+func AcquireIterator(code []byte) *instructionIterator { log.DebugLog()
+	it := iteratorPool.Get().(*instructionIterator)
+	*it = instructionIterator{code: code, fork: Latest}
+	return it
+}
+
+// Returns an iterator obtained from AcquireIterator to the pool. Its state,
+// including the reference to the code, is cleared. A released iterator must
+// not be used again.
+func ReleaseIterator(it *instructionIterator) { log.DebugLog()
+	*it = instructionIterator{}
+	iteratorPool.Put(it)
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that pooled iterators behave like fresh ones and drop their code
+func TestAcquireIterator(t *testing.T) { log.DebugLog()
+	code := []byte{byte(vm.PUSH1), 0x01, byte(vm.ADD)}
+	for i := 0; i < 3; i++ {
+		it := AcquireIterator(code)
+		if it.Started() || it.PC() != 0 || it.Error() != nil {
+			t.Fatalf("Expected fresh iterator, but got started %v at %d (%v).", it.Started(), it.PC(), it.Error())
+		}
+		cnt := 0
+		for it.Next() {
+			cnt++
+		}
+		if cnt != 2 {
+			t.Errorf("Expected 2 instructions, but got %d instead.", cnt)
+		}
+		ReleaseIterator(it)
+		if it.code != nil {
+			t.Errorf("Expected released iterator to drop the code.")
+		}
+	}
+}
+
+// Benchmarks iterating with fresh iterators
+func BenchmarkNewInstructionIterator(b *testing.B) { log.DebugLog()
+	code := []byte{byte(vm.PUSH1), 0x01, byte(vm.ADD)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		it := NewInstructionIterator(code)
+		for it.Next() {
+		}
+	}
+}
+
+// Benchmarks iterating with pooled iterators
+func BenchmarkAcquireIterator(b *testing.B) { log.DebugLog()
+	code := []byte{byte(vm.PUSH1), 0x01, byte(vm.ADD)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		it := AcquireIterator(code)
+		for it.Next() {
+		}
+		ReleaseIterator(it)
+	}
+}
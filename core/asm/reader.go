@@ -0,0 +1,94 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Iterator for disassembled EVM instructions streamed from a reader, so the
+// code never needs to be held in memory as a whole.
+type readerIterator struct {
+	r     *bufio.Reader
+	pc    uint64
+	next  uint64
+	arg   []byte
+	op    vm.OpCode
+	error error
+}
+
+// Create a new instruction iterator reading the code from r.
+func NewReaderIterator(r io.Reader) *readerIterator { log.DebugLog()
+	return &readerIterator{r: bufio.NewReader(r)}
+}
+
+// Returns true if there is a next instruction and moves on.
+func (it *readerIterator) Next() bool { log.DebugLog()
+	if it.error != nil {
+		return false
+	}
+	b, err := it.r.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			it.error = err
+		}
+		return false
+	}
+	it.pc, it.op, it.arg = it.next, vm.OpCode(b), nil
+
+	if n, ok := PushWidth(it.op); ok {
+		it.arg = make([]byte, n)
+		have, err := io.ReadFull(it.r, it.arg)
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			it.arg = it.arg[:have]
+			it.error = &ErrTruncatedPush{PC: it.pc, Op: it.op, Need: n, Have: have}
+			return false
+		case err != nil:
+			it.error = err
+			return false
+		}
+	}
+	it.next = it.pc + 1 + uint64(len(it.arg))
+	return true
+}
+
+// Returns any error that may have been encountered, either while reading or
+// because of a truncated push.
+func (it *readerIterator) Error() error { log.DebugLog()
+	return it.error
+}
+
+// Returns the PC of the current instruction.
+func (it *readerIterator) PC() uint64 { log.DebugLog()
+	return it.pc
+}
+
+// Returns the opcode of the current instruction.
+func (it *readerIterator) Op() vm.OpCode { log.DebugLog()
+	return it.op
+}
+
+// Returns the argument of the current instruction. The slice is owned by the
+// caller.
+func (it *readerIterator) Arg() []byte { log.DebugLog()
+	return it.arg
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that streamed decoding matches the in-memory iterator
+func TestReaderIterator(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("6080604052" + "5f" + "7f" + "0102030405060708091011121314151617181920212223242526272829303132" + "00")
+
+	want := NewInstructionIterator(script)
+	// Read one byte at a time to exercise the buffering
+	it := NewReaderIterator(iotest.OneByteReader(bytes.NewReader(script)))
+	for want.Next() {
+		if !it.Next() {
+			t.Fatalf("Expected %v at %d, but iterator stopped with %v.", want.Op(), want.PC(), it.Error())
+		}
+		if it.PC() != want.PC() || it.Op() != want.Op() || !bytes.Equal(it.Arg(), want.Arg()) {
+			t.Errorf("Expected %d %v %x, but got %d %v %x.", want.PC(), want.Op(), want.Arg(), it.PC(), it.Op(), it.Arg())
+		}
+	}
+	if it.Next() || it.Error() != nil {
+		t.Errorf("Expected clean end, but got error %v.", it.Error())
+	}
+}
+
+// Tests the errors of streamed decoding
+func TestReaderIteratorErrors(t *testing.T) { log.DebugLog()
+	it := NewReaderIterator(bytes.NewReader([]byte{0x01, 0x62, 0xaa}))
+	it.Next()
+	if it.Next() {
+		t.Fatalf("Expected truncated push to fail.")
+	}
+	var perr *ErrTruncatedPush
+	if !errors.As(it.Error(), &perr) || perr.PC != 1 || perr.Need != 3 || perr.Have != 1 || !bytes.Equal(it.Arg(), []byte{0xaa}) {
+		t.Errorf("Expected truncated PUSH3 at 1, but got %v with %x.", it.Error(), it.Arg())
+	}
+	failure := errors.New("read failure")
+	it = NewReaderIterator(iotest.ErrReader(failure))
+	if it.Next() || it.Error() != failure {
+		t.Errorf("Expected %v, but got %v instead.", failure, it.Error())
+	}
+}
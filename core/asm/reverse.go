@@ -0,0 +1,77 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Iterator for disassembled EVM instructions from the last to the first.
+// Since the encoding cannot be decoded backwards, the offsets of all
+// instructions are recorded up front, using O(n) memory in the code size.
+type reverseIterator struct {
+	code  []byte
+	pcs   []uint64
+	index int
+	arg   []byte
+	op    vm.OpCode
+}
+
+// Create a new reverse instruction iterator. An error is returned if the code
+// is truncated.
+func NewReverseIterator(code []byte) (*reverseIterator, error) { log.DebugLog()
+	var pcs []uint64
+
+	it := NewInstructionIterator(code)
+	for it.Next() {
+		pcs = append(pcs, it.PC())
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return &reverseIterator{code: code, pcs: pcs, index: len(pcs)}, nil
+}
+
+// Returns true if there is a previous instruction and moves on to it.
+func (it *reverseIterator) Next() bool { log.DebugLog()
+	if it.index == 0 {
+		return false
+	}
+	it.index--
+	it.op, it.arg, _ = decodeInstruction(it.code, it.pcs[it.index], Latest)
+	return true
+}
+
+// Returns the PC of the current instruction, or 0 before the first call to
+// Next.
+func (it *reverseIterator) PC() uint64 { log.DebugLog()
+	if it.index >= len(it.pcs) {
+		return 0
+	}
+	return it.pcs[it.index]
+}
+
+// Returns the opcode of the current instruction.
+func (it *reverseIterator) Op() vm.OpCode { log.DebugLog()
+	return it.op
+}
+
+// Returns the argument of the current instruction.
+func (it *reverseIterator) Arg() []byte { log.DebugLog()
+	return it.arg
+}
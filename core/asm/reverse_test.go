@@ -0,0 +1,66 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests iterating over instructions from last to first
+func TestReverseIterator(t *testing.T) { log.DebugLog()
+	// PUSH2 0x5b5b CALLER SSTORE
+	script, _ := hex.DecodeString("615b5b3355")
+
+	it, err := NewReverseIterator(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if it.PC() != 0 {
+		t.Errorf("Expected pc 0 before the first instruction, but got %d.", it.PC())
+	}
+	want := []struct {
+		pc  uint64
+		op  vm.OpCode
+		arg []byte
+	}{
+		{4, vm.SSTORE, nil},
+		{3, vm.CALLER, nil},
+		{0, vm.PUSH2, []byte{0x5b, 0x5b}},
+	}
+	for i, w := range want {
+		if !it.Next() {
+			t.Fatalf("instruction %d: expected more instructions.", i)
+		}
+		if it.PC() != w.pc || it.Op() != w.op || !bytes.Equal(it.Arg(), w.arg) {
+			t.Errorf("instruction %d: expected %d %v %x, but got %d %v %x.", i, w.pc, w.op, w.arg, it.PC(), it.Op(), it.Arg())
+		}
+	}
+	if it.Next() {
+		t.Errorf("Expected iteration to end, but got %v.", it.Op())
+	}
+	if empty, err := NewReverseIterator(nil); err != nil || empty.PC() != 0 || empty.Next() {
+		t.Errorf("Expected empty iteration at pc 0, but got %v.", err)
+	}
+	if _, err := NewReverseIterator([]byte{byte(vm.PUSH2), 0x01}); err == nil {
+		t.Errorf("Expected truncation error, but got none.")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// SourceMapEntry is the decompressed source mapping of a single instruction.
+type SourceMapEntry struct {
+	Start     int    // byte offset of the source range
+	Length    int    // length of the source range
+	FileIndex int    // index of the source file, -1 if there is none
+	JumpType  string // "i" into a function, "o" out of it, "-" regular jump
+}
+
+// AnnotatedInstruction is a decoded instruction with its source mapping.
+type AnnotatedInstruction struct {
+	Instruction
+	Source *SourceMapEntry // nil if the source map has no entry for it
+}
+
+// parseSourceMap expands a Solidity source map in the compressed s:l:f:j form,
+// where empty fields and entries repeat the values of the previous entry.
+func parseSourceMap(srcmap string) ([]SourceMapEntry, error) { log.DebugLog()
+	if srcmap == "" {
+		return nil, nil
+	}
+	var (
+		entries []SourceMapEntry
+		last    = SourceMapEntry{FileIndex: -1, JumpType: "-"}
+	)
+	for i, item := range strings.Split(srcmap, ";") {
+		for k, field := range strings.Split(item, ":") {
+			if field == "" {
+				continue
+			}
+			switch k {
+			case 0, 1, 2:
+				n, err := strconv.Atoi(field)
+				if err != nil {
+					return nil, fmt.Errorf("source map entry %d: invalid field %q", i, field)
+				}
+				switch k {
+				case 0:
+					last.Start = n
+				case 1:
+					last.Length = n
+				case 2:
+					last.FileIndex = n
+				}
+			case 3:
+				if field != "i" && field != "o" && field != "-" {
+					return nil, fmt.Errorf("source map entry %d: invalid jump type %q", i, field)
+				}
+				last.JumpType = field
+			}
+		}
+		entries = append(entries, last)
+	}
+	return entries, nil
+}
+
+// DisassembleWithSourceMap decodes the code and attaches the matching entry of
+// the Solidity source map to every instruction. Source map entries beyond the
+// number of instructions are ignored.
+func DisassembleWithSourceMap(script []byte, srcmap string) ([]AnnotatedInstruction, error) { log.DebugLog()
+	entries, err := parseSourceMap(srcmap)
+	if err != nil {
+		return nil, err
+	}
+	instrs, err := DisassembleInstructions(script)
+	if err != nil {
+		return nil, err
+	}
+	annotated := make([]AnnotatedInstruction, len(instrs))
+	for i, instr := range instrs {
+		annotated[i].Instruction = instr
+		if i < len(entries) {
+			annotated[i].Source = &entries[i]
+		}
+	}
+	return annotated, nil
+}
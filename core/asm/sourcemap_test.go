@@ -0,0 +1,62 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests expanding the compressed source map onto instructions
+func TestDisassembleWithSourceMap(t *testing.T) { log.DebugLog()
+	// PUSH1 0x80 PUSH1 0x40 MSTORE JUMP STOP
+	script, _ := hex.DecodeString("60806040525600")
+
+	instrs, err := DisassembleWithSourceMap(script, "0:10:0:-;;12:3;:5::i;2:1:-1:o;99:1:0:-")
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []SourceMapEntry{
+		{0, 10, 0, "-"},
+		{0, 10, 0, "-"},
+		{12, 3, 0, "-"},
+		{12, 5, 0, "i"},
+		{2, 1, -1, "o"},
+	}
+	if len(instrs) != len(want) {
+		t.Fatalf("Expected %d instructions, but got %d instead.", len(want), len(instrs))
+	}
+	for i, instr := range instrs {
+		if instr.Source == nil || *instr.Source != want[i] {
+			t.Errorf("instruction %d: expected %+v, but got %+v instead.", i, want[i], instr.Source)
+		}
+	}
+	if instrs[2].PC != 4 {
+		t.Errorf("Expected third instruction at 4, but got %d.", instrs[2].PC)
+	}
+
+	// Instructions without a map entry carry no source
+	instrs, _ = DisassembleWithSourceMap(script, "1:2:0")
+	if instrs[0].Source == nil || instrs[1].Source != nil {
+		t.Errorf("Expected only the first instruction to be mapped, but got %+v.", instrs)
+	}
+	if _, err := DisassembleWithSourceMap(script, "1:x"); err == nil {
+		t.Errorf("Expected an error, but got none.")
+	}
+}
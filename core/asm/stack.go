@@ -0,0 +1,108 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// stackEffect returns the number of stack items op pops and pushes, matching
+// the stack validation of the instruction sets in core/vm. DUPn and SWAPn are
+// accounted as reading and rewriting the items they touch. Undefined opcodes
+// have no effect.
+func stackEffect(op vm.OpCode) (pops int, pushes int) { log.DebugLog()
+	switch {
+	case op.IsPush():
+		return 0, 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		n := int(op-vm.DUP1) + 1
+		return n, n + 1
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		n := int(op-vm.SWAP1) + 2
+		return n, n
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return int(op-vm.LOG0) + 2, 0
+	}
+	switch op {
+	case vm.ADD, vm.MUL, vm.SUB, vm.DIV, vm.SDIV, vm.MOD, vm.SMOD, vm.EXP, vm.SIGNEXTEND,
+		vm.LT, vm.GT, vm.SLT, vm.SGT, vm.EQ, vm.AND, vm.OR, vm.XOR, vm.BYTE,
+		vm.SHL, vm.SHR, vm.SAR, vm.SHA3:
+		return 2, 1
+	case vm.ADDMOD, vm.MULMOD, vm.CREATE:
+		return 3, 1
+	case vm.ISZERO, vm.NOT, vm.BALANCE, vm.CALLDATALOAD, vm.EXTCODESIZE, vm.BLOCKHASH,
+		vm.MLOAD, vm.SLOAD:
+		return 1, 1
+	case vm.ADDRESS, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATASIZE, vm.CODESIZE,
+		vm.GASPRICE, vm.RETURNDATASIZE, vm.COINBASE, vm.TIMESTAMP, vm.NUMBER,
+		vm.DIFFICULTY, vm.GASLIMIT, vm.PC, vm.MSIZE, vm.GAS, vm.PUSH0:
+		return 0, 1
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		return 3, 0
+	case vm.EXTCODECOPY:
+		return 4, 0
+	case vm.CREATE2:
+		return 4, 1
+	case vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.JUMPI, vm.RETURN, vm.REVERT:
+		return 2, 0
+	case vm.POP, vm.JUMP, vm.SELFDESTRUCT:
+		return 1, 0
+	case vm.CALL, vm.CALLCODE:
+		return 7, 1
+	case vm.DELEGATECALL, vm.STATICCALL:
+		return 6, 1
+	}
+	return 0, 0
+}
+
+// Returns the number of stack items the current instruction pops and pushes.
+func (it *instructionIterator) StackDelta() (pops int, pushes int) { log.DebugLog()
+	return stackEffect(it.op)
+}
+
+// Return all disassembled EVM instructions in human-readable format, each
+// annotated with the stack height after executing the code linearly from an
+// empty stack. Instructions that would pop more items than available are
+// flagged, which usually marks malformed code or data decoded as code.
+func DisassembleWithStack(script []byte) ([]string, error) { log.DebugLog()
+	instrs := make([]string, 0, len(script)/2)
+	height := 0
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		pops, pushes := it.StackDelta()
+		flag := ""
+		if height < pops {
+			flag = " (underflow)"
+		}
+		height += pushes - pops
+
+		instrs = append(instrs, fmt.Sprintf("%v [stack %d]%s\n", it.current(), height, flag))
+
+		// Only flag the underflowing instruction itself, not everything after
+		if height < 0 {
+			height = 0
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return instrs, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests the stack effect of a selection of opcodes
+func TestStackDelta(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		op           vm.OpCode
+		pops, pushes int
+	}{
+		{vm.STOP, 0, 0},
+		{vm.ADD, 2, 1},
+		{vm.PUSH32, 0, 1},
+		{vm.DUP3, 3, 4},
+		{vm.SWAP1, 2, 2},
+		{vm.LOG4, 6, 0},
+		{vm.CALL, 7, 1},
+		{vm.STATICCALL, 6, 1},
+		{vm.OpCode(0x0c), 0, 0},
+	}
+	for _, test := range tests {
+		it := NewInstructionIterator([]byte{byte(test.op)})
+		it.Next()
+		if pops, pushes := it.StackDelta(); pops != test.pops || pushes != test.pushes {
+			t.Errorf("%v: expected %d/%d, but got %d/%d instead.", test.op, test.pops, test.pushes, pops, pushes)
+		}
+	}
+}
+
+// Tests the running stack height annotation
+func TestDisassembleWithStack(t *testing.T) { log.DebugLog()
+	// PUSH1 0x01 DUP1 ADD POP ADD PUSH1 0x01
+	script, _ := hex.DecodeString("6001800150016001")
+
+	instrs, err := DisassembleWithStack(script)
+	if err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{
+		"000000: PUSH1 0x01 [stack 1]\n",
+		"000002: DUP1 [stack 2]\n",
+		"000003: ADD [stack 1]\n",
+		"000004: POP [stack 0]\n",
+		"000005: ADD [stack -1] (underflow)\n",
+		"000006: PUSH1 0x01 [stack 1]\n",
+	}
+	if !reflect.DeepEqual(instrs, want) {
+		t.Errorf("Expected %q, but got %q instead.", want, instrs)
+	}
+}
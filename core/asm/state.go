@@ -0,0 +1,83 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Version of the encoding produced by MarshalState.
+const stateVersion = 1
+
+const (
+	stateStarted  = 1 << iota // Iteration has been started
+	stateHasError             // Iteration stopped with an error
+)
+
+// Returns a compact, versioned checkpoint of the iteration progress, which
+// can be restored with UnmarshalState on an iterator over the same code. The
+// code itself is not included.
+func (it *instructionIterator) MarshalState() []byte { log.DebugLog()
+	b := make([]byte, 10)
+	b[0] = stateVersion
+	if it.started {
+		b[1] |= stateStarted
+	}
+	if it.error != nil {
+		b[1] |= stateHasError
+	}
+	binary.BigEndian.PutUint64(b[2:], it.pc)
+	return b
+}
+
+// Restores a checkpoint created by MarshalState, re-decoding the current
+// instruction from the code. An error is returned if the checkpoint is
+// malformed or does not match the code, such as a pc inside push data, in
+// which case the iterator is left untouched.
+func (it *instructionIterator) UnmarshalState(b []byte) error { log.DebugLog()
+	if len(b) != 10 {
+		return fmt.Errorf("invalid iterator state length %d", len(b))
+	}
+	if b[0] != stateVersion {
+		return fmt.Errorf("unsupported iterator state version %d", b[0])
+	}
+	pc := binary.BigEndian.Uint64(b[2:])
+	if !it.isBoundary(pc) {
+		return fmt.Errorf("iterator state pc %d is not an instruction boundary", pc)
+	}
+	// Restore into a copy, so a mismatch leaves the iterator untouched
+	restored := *it
+	restored.Reset()
+	restored.started = b[1]&stateStarted != 0
+	restored.pc = pc
+
+	if restored.started && restored.pc < uint64(len(restored.code)) {
+		restored.op, restored.arg, restored.error = decodeInstruction(restored.code, restored.pc, restored.fork)
+		if restored.lenient {
+			restored.error = nil
+		}
+	}
+	if (restored.error != nil) != (b[1]&stateHasError != 0) {
+		return errors.New("iterator state does not match the code")
+	}
+	*it = restored
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests resuming the iteration from a checkpoint
+func TestInstructionIteratorMarshalState(t *testing.T) { log.DebugLog()
+	// PUSH1 0x01 PUSH1 0x02 ADD PUSH2 (truncated)
+	script, _ := hex.DecodeString("600160020161ff")
+
+	it := NewInstructionIterator(script)
+	it.Next()
+	it.Next()
+	state := it.MarshalState()
+	if len(state) != 10 || state[0] != stateVersion {
+		t.Fatalf("Expected 10 byte state with version %d, but got %x.", stateVersion, state)
+	}
+	resumed := NewInstructionIterator(script)
+	if err := resumed.UnmarshalState(state); err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	if resumed.PC() != 2 || resumed.Op() != it.Op() || resumed.ArgHex() != "02" {
+		t.Errorf("Expected PUSH1 0x02 at 2, but got %v 0x%s at %d.", resumed.Op(), resumed.ArgHex(), resumed.PC())
+	}
+	for it.Next() && resumed.Next() {
+		if it.PC() != resumed.PC() {
+			t.Errorf("Expected pc %d, but got %d instead.", it.PC(), resumed.PC())
+		}
+	}
+	// The error at the end is restored as well
+	state = it.MarshalState()
+	resumed = NewInstructionIterator(script)
+	if err := resumed.UnmarshalState(state); err != nil || resumed.Error() == nil || !resumed.Done() {
+		t.Errorf("Expected restored error, but got %v (%v).", resumed.Error(), err)
+	}
+	// A fresh state restores a fresh iterator
+	resumed = NewInstructionIterator(script)
+	if err := resumed.UnmarshalState(NewInstructionIterator(script).MarshalState()); err != nil || resumed.Started() {
+		t.Errorf("Expected fresh iterator, but got started %v (%v).", resumed.Started(), err)
+	}
+}
+
+// Tests rejecting malformed or mismatching checkpoints, leaving the iterator
+// untouched
+func TestInstructionIteratorMarshalStateErrors(t *testing.T) { log.DebugLog()
+	script, _ := hex.DecodeString("600160020161ff")
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+	}
+	state := it.MarshalState()
+
+	// unmarshal restores b into an iterator positioned at PUSH1 0x02
+	unmarshal := func(code []byte, b []byte) error {
+		it := NewInstructionIterator(code)
+		it.Next()
+		it.Next()
+		err := it.UnmarshalState(b)
+		if !it.Started() || it.PC() != 2 || it.ArgHex() != "02" || it.Error() != nil {
+			t.Errorf("state %x: expected PUSH1 0x02 at 2 to be kept, but got %v 0x%s at %d.", b, it.Op(), it.ArgHex(), it.PC())
+		}
+		return err
+	}
+	for _, b := range [][]byte{nil, state[:9], append([]byte{2}, state[1:]...)} {
+		if err := unmarshal(script, b); err == nil {
+			t.Errorf("state %x: expected error, but got none.", b)
+		}
+	}
+	if err := unmarshal(script[:5], state); err == nil {
+		t.Errorf("Expected mismatch error, but got none.")
+	}
+	// A pc inside push data or beyond the code must not be decoded
+	for _, pc := range []uint64{1, 3, 8} {
+		it := NewInstructionIterator(script)
+		it.pc = pc
+		it.started = true
+		if err := unmarshal(script, it.MarshalState()); err == nil {
+			t.Errorf("pc %d: expected error, but got none.", pc)
+		}
+	}
+}